@@ -3,7 +3,11 @@ module github.com/max-programming/gator
 go 1.24.6
 
 require (
-	github.com/google/uuid v1.6.0 // indirect
-	github.com/lib/pq v1.10.9 // indirect
-	github.com/timematic/anytime v0.0.0-20250424004116-93a49dc8f85f // indirect
+	github.com/andybalholm/brotli v1.2.0
+	github.com/google/uuid v1.6.0
+	github.com/lib/pq v1.10.9
+	github.com/timematic/anytime v0.0.0-20250424004116-93a49dc8f85f
+	golang.org/x/net v0.50.0
 )
+
+require golang.org/x/text v0.34.0 // indirect
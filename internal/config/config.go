@@ -4,13 +4,50 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 const configFileName = ".gatorconfig.json"
 
 type Config struct {
-	DBUrl           string `json:"db_url"`
-	CurrentUserName string `json:"current_user_name"`
+	DBUrl           string      `json:"db_url"`
+	CurrentUserName string      `json:"current_user_name"`
+	Fetch           FetchConfig `json:"fetch,omitzero"`
+}
+
+// FetchConfig tunes the feed HTTP client. Zero values fall back to the
+// client's defaults.
+type FetchConfig struct {
+	ConnectTimeout Duration `json:"connect_timeout,omitzero"`
+	ReadTimeout    Duration `json:"read_timeout,omitzero"`
+	MaxBodyBytes   int64    `json:"max_body_bytes,omitzero"`
+	MaxRedirects   int      `json:"max_redirects,omitzero"`
+	// HostConcurrency and HostDelay keep the aggregator polite towards hosts
+	// that serve many feeds.
+	HostConcurrency int      `json:"per_host_concurrency,omitzero"`
+	HostDelay       Duration `json:"per_host_delay,omitzero"`
+}
+
+// Duration is stored in the config file as a time.ParseDuration string
+// such as "30s".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
 }
 
 func Read() (Config, error) {
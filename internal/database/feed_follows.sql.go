@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,11 +15,18 @@ import (
 
 const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
-  INSERT INTO feed_follows (id, user_id, feed_id, created_at, updated_at)
-  VALUES ($1, $2, $3, $4, $5)
-  RETURNING id, user_id, feed_id, created_at, updated_at
+  INSERT INTO feed_follows (
+      id,
+      user_id,
+      feed_id,
+      created_at,
+      updated_at,
+      folder
+    )
+  VALUES ($1, $2, $3, $4, $5, $6)
+  RETURNING id, user_id, feed_id, created_at, updated_at, folder
 )
-SELECT inserted_feed_follow.id, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at,
+SELECT inserted_feed_follow.id, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.folder,
   feeds.name AS feed_name,
   users.name AS user_name
 FROM inserted_feed_follow
@@ -32,6 +40,7 @@ type CreateFeedFollowParams struct {
 	FeedID    uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Folder    sql.NullString
 }
 
 type CreateFeedFollowRow struct {
@@ -40,6 +49,7 @@ type CreateFeedFollowRow struct {
 	FeedID    uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Folder    sql.NullString
 	FeedName  string
 	UserName  string
 }
@@ -51,6 +61,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		arg.FeedID,
 		arg.CreatedAt,
 		arg.UpdatedAt,
+		arg.Folder,
 	)
 	var i CreateFeedFollowRow
 	err := row.Scan(
@@ -59,6 +70,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.FeedID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Folder,
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -82,9 +94,21 @@ func (q *Queries) DeleteFeedFollowByUserIDAndFeedID(ctx context.Context, arg Del
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT ff.id, ff.user_id, ff.feed_id, ff.created_at, ff.updated_at,
+SELECT ff.id, ff.user_id, ff.feed_id, ff.created_at, ff.updated_at, ff.folder,
   f.name AS feed_name,
-  u.name AS user_name
+  f.url AS feed_url,
+  f.link AS feed_link,
+  u.name AS user_name,
+  f.disabled_at AS feed_disabled_at,
+  f.gone_at AS feed_gone_at,
+  (
+    SELECT COUNT(*)
+    FROM posts p
+      LEFT JOIN post_states ps ON ps.post_id = p.id
+      AND ps.user_id = ff.user_id
+    WHERE p.feed_id = ff.feed_id
+      AND NOT COALESCE(ps.read, FALSE)
+  ) AS unread_count
 FROM feed_follows ff
   JOIN users u ON u.id = ff.user_id
   JOIN feeds f ON f.id = ff.feed_id
@@ -92,13 +116,19 @@ WHERE ff.user_id = $1
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID        uuid.UUID
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	FeedName  string
-	UserName  string
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	FeedID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Folder         sql.NullString
+	FeedName       string
+	FeedUrl        string
+	FeedLink       sql.NullString
+	UserName       string
+	FeedDisabledAt sql.NullTime
+	FeedGoneAt     sql.NullTime
+	UnreadCount    int64
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -116,8 +146,14 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.FeedID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Folder,
 			&i.FeedName,
+			&i.FeedUrl,
+			&i.FeedLink,
 			&i.UserName,
+			&i.FeedDisabledAt,
+			&i.FeedGoneAt,
+			&i.UnreadCount,
 		); err != nil {
 			return nil, err
 		}
@@ -131,3 +167,24 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	}
 	return items, nil
 }
+
+const moveFeedFollows = `-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET feed_id = $1
+WHERE feed_id = $2
+  AND user_id NOT IN (
+    SELECT user_id
+    FROM feed_follows
+    WHERE feed_id = $1
+  )
+`
+
+type MoveFeedFollowsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollows, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
@@ -13,19 +13,126 @@ import (
 	"github.com/google/uuid"
 )
 
+const claimFeedsToFetch = `-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET last_fetched_at = $1::timestamp,
+  next_fetch_at = $2::timestamp,
+  updated_at = $1::timestamp
+WHERE id IN (
+    SELECT id
+    FROM feeds
+    WHERE disabled_at IS NULL
+      AND (
+        user_id = $3
+        OR (
+          $3::uuid IS NULL
+          AND EXISTS (
+            SELECT 1
+            FROM feed_follows ff
+            WHERE ff.feed_id = feeds.id
+          )
+        )
+      )
+      AND (
+        next_fetch_at IS NULL
+        OR next_fetch_at <= $4::timestamp
+      )
+    ORDER BY next_fetch_at ASC NULLS FIRST
+    LIMIT $5
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING id, name, url, user_id, created_at, updated_at, last_fetched_at, refresh_interval_seconds, next_fetch_at, etag, last_modified, error_count, last_error, last_success_at, disabled_at, gone_at, title, link, description, language, image_url
+`
+
+type ClaimFeedsToFetchParams struct {
+	FetchedAt  time.Time
+	LeaseUntil time.Time
+	UserID     uuid.NullUUID
+	DueBefore  time.Time
+	BatchSize  int32
+}
+
+func (q *Queries) ClaimFeedsToFetch(ctx context.Context, arg ClaimFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimFeedsToFetch,
+		arg.FetchedAt,
+		arg.LeaseUntil,
+		arg.UserID,
+		arg.DueBefore,
+		arg.BatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LastFetchedAt,
+			&i.RefreshIntervalSeconds,
+			&i.NextFetchAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.ErrorCount,
+			&i.LastError,
+			&i.LastSuccessAt,
+			&i.DisabledAt,
+			&i.GoneAt,
+			&i.Title,
+			&i.Link,
+			&i.Description,
+			&i.Language,
+			&i.ImageUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createFeed = `-- name: CreateFeed :one
-INSERT INTO feeds (id, name, url, user_id, created_at, updated_at)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, name, url, user_id, created_at, updated_at, last_fetched_at
+INSERT INTO feeds (
+    id,
+    name,
+    url,
+    user_id,
+    created_at,
+    updated_at,
+    title,
+    link,
+    description,
+    language,
+    image_url
+  )
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id, name, url, user_id, created_at, updated_at, last_fetched_at, refresh_interval_seconds, next_fetch_at, etag, last_modified, error_count, last_error, last_success_at, disabled_at, gone_at, title, link, description, language, image_url
 `
 
 type CreateFeedParams struct {
-	ID        uuid.UUID
-	Name      string
-	Url       string
-	UserID    uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID          uuid.UUID
+	Name        string
+	Url         string
+	UserID      uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       sql.NullString
+	Link        sql.NullString
+	Description sql.NullString
+	Language    sql.NullString
+	ImageUrl    sql.NullString
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -36,6 +143,11 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.UserID,
 		arg.CreatedAt,
 		arg.UpdatedAt,
+		arg.Title,
+		arg.Link,
+		arg.Description,
+		arg.Language,
+		arg.ImageUrl,
 	)
 	var i Feed
 	err := row.Scan(
@@ -46,12 +158,36 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastFetchedAt,
+		&i.RefreshIntervalSeconds,
+		&i.NextFetchAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ErrorCount,
+		&i.LastError,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.GoneAt,
+		&i.Title,
+		&i.Link,
+		&i.Description,
+		&i.Language,
+		&i.ImageUrl,
 	)
 	return i, err
 }
 
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, name, url, user_id, created_at, updated_at, last_fetched_at
+SELECT id, name, url, user_id, created_at, updated_at, last_fetched_at, refresh_interval_seconds, next_fetch_at, etag, last_modified, error_count, last_error, last_success_at, disabled_at, gone_at, title, link, description, language, image_url
 FROM feeds
 WHERE url = $1
 `
@@ -67,6 +203,20 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastFetchedAt,
+		&i.RefreshIntervalSeconds,
+		&i.NextFetchAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ErrorCount,
+		&i.LastError,
+		&i.LastSuccessAt,
+		&i.DisabledAt,
+		&i.GoneAt,
+		&i.Title,
+		&i.Link,
+		&i.Description,
+		&i.Language,
+		&i.ImageUrl,
 	)
 	return i, err
 }
@@ -74,15 +224,21 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 const getFeeds = `-- name: GetFeeds :many
 SELECT f.name,
   f.url,
+  f.error_count,
+  f.last_error,
+  f.disabled_at,
   u.name AS username
 FROM feeds f
   JOIN users u ON u.id = f.user_id
 `
 
 type GetFeedsRow struct {
-	Name     string
-	Url      string
-	Username string
+	Name       string
+	Url        string
+	ErrorCount int32
+	LastError  sql.NullString
+	DisabledAt sql.NullTime
+	Username   string
 }
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
@@ -94,7 +250,14 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	var items []GetFeedsRow
 	for rows.Next() {
 		var i GetFeedsRow
-		if err := rows.Scan(&i.Name, &i.Url, &i.Username); err != nil {
+		if err := rows.Scan(
+			&i.Name,
+			&i.Url,
+			&i.ErrorCount,
+			&i.LastError,
+			&i.DisabledAt,
+			&i.Username,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -108,50 +271,228 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, name, url, user_id, created_at, updated_at, last_fetched_at
+const getFeedsByURLOrName = `-- name: GetFeedsByURLOrName :many
+SELECT id, name, url, user_id, created_at, updated_at, last_fetched_at, refresh_interval_seconds, next_fetch_at, etag, last_modified, error_count, last_error, last_success_at, disabled_at, gone_at, title, link, description, language, image_url
 FROM feeds
-WHERE user_id = $1
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1
+WHERE url = $1
+  OR name = $1
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context, userID uuid.UUID) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch, userID)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.LastFetchedAt,
+func (q *Queries) GetFeedsByURLOrName(ctx context.Context, urlOrName string) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsByURLOrName, urlOrName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LastFetchedAt,
+			&i.RefreshIntervalSeconds,
+			&i.NextFetchAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.ErrorCount,
+			&i.LastError,
+			&i.LastSuccessAt,
+			&i.DisabledAt,
+			&i.GoneAt,
+			&i.Title,
+			&i.Link,
+			&i.Description,
+			&i.Language,
+			&i.ImageUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markFeedGone = `-- name: MarkFeedGone :exec
+UPDATE feeds
+SET error_count = error_count + 1,
+  last_error = $1,
+  gone_at = $2,
+  disabled_at = $2,
+  updated_at = $2
+WHERE id = $3
+`
+
+type MarkFeedGoneParams struct {
+	LastError sql.NullString
+	GoneAt    sql.NullTime
+	ID        uuid.UUID
+}
+
+func (q *Queries) MarkFeedGone(ctx context.Context, arg MarkFeedGoneParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedGone, arg.LastError, arg.GoneAt, arg.ID)
+	return err
+}
+
+const recordFeedFailure = `-- name: RecordFeedFailure :exec
+UPDATE feeds
+SET error_count = error_count + 1,
+  last_error = $1,
+  next_fetch_at = $2,
+  disabled_at = COALESCE(disabled_at, $3::timestamp),
+  updated_at = $4
+WHERE id = $5
+`
+
+type RecordFeedFailureParams struct {
+	LastError   sql.NullString
+	NextFetchAt sql.NullTime
+	DisabledAt  sql.NullTime
+	UpdatedAt   time.Time
+	ID          uuid.UUID
+}
+
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedFailure,
+		arg.LastError,
+		arg.NextFetchAt,
+		arg.DisabledAt,
+		arg.UpdatedAt,
+		arg.ID,
 	)
-	return i, err
+	return err
 }
 
-const markFeedFetched = `-- name: MarkFeedFetched :exec
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
 UPDATE feeds
-SET last_fetched_at = $1,
+SET error_count = 0,
+  last_error = NULL,
+  disabled_at = NULL,
+  gone_at = NULL,
+  last_success_at = $1,
   updated_at = $2
 WHERE id = $3
-  AND user_id = $4
 `
 
-type MarkFeedFetchedParams struct {
-	LastFetchedAt sql.NullTime
+type RecordFeedSuccessParams struct {
+	LastSuccessAt sql.NullTime
 	UpdatedAt     time.Time
 	ID            uuid.UUID
-	UserID        uuid.UUID
 }
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched,
-		arg.LastFetchedAt,
+func (q *Queries) RecordFeedSuccess(ctx context.Context, arg RecordFeedSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess, arg.LastSuccessAt, arg.UpdatedAt, arg.ID)
+	return err
+}
+
+const scheduleNextFetch = `-- name: ScheduleNextFetch :exec
+UPDATE feeds
+SET refresh_interval_seconds = $1,
+  next_fetch_at = $2,
+  updated_at = $3
+WHERE id = $4
+`
+
+type ScheduleNextFetchParams struct {
+	RefreshIntervalSeconds sql.NullInt32
+	NextFetchAt            sql.NullTime
+	UpdatedAt              time.Time
+	ID                     uuid.UUID
+}
+
+func (q *Queries) ScheduleNextFetch(ctx context.Context, arg ScheduleNextFetchParams) error {
+	_, err := q.db.ExecContext(ctx, scheduleNextFetch,
+		arg.RefreshIntervalSeconds,
+		arg.NextFetchAt,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const setFeedCacheValidators = `-- name: SetFeedCacheValidators :exec
+UPDATE feeds
+SET etag = $1,
+  last_modified = $2,
+  updated_at = $3
+WHERE id = $4
+`
+
+type SetFeedCacheValidatorsParams struct {
+	Etag         sql.NullString
+	LastModified sql.NullString
+	UpdatedAt    time.Time
+	ID           uuid.UUID
+}
+
+func (q *Queries) SetFeedCacheValidators(ctx context.Context, arg SetFeedCacheValidatorsParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedCacheValidators,
+		arg.Etag,
+		arg.LastModified,
 		arg.UpdatedAt,
 		arg.ID,
-		arg.UserID,
 	)
 	return err
 }
+
+const updateFeedMetadata = `-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $1,
+  link = $2,
+  description = $3,
+  language = $4,
+  image_url = $5,
+  updated_at = $6
+WHERE id = $7
+`
+
+type UpdateFeedMetadataParams struct {
+	Title       sql.NullString
+	Link        sql.NullString
+	Description sql.NullString
+	Language    sql.NullString
+	ImageUrl    sql.NullString
+	UpdatedAt   time.Time
+	ID          uuid.UUID
+}
+
+func (q *Queries) UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedMetadata,
+		arg.Title,
+		arg.Link,
+		arg.Description,
+		arg.Language,
+		arg.ImageUrl,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $1,
+  updated_at = $2
+WHERE id = $3
+`
+
+type UpdateFeedURLParams struct {
+	Url       string
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedURL, arg.Url, arg.UpdatedAt, arg.ID)
+	return err
+}
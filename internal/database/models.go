@@ -12,13 +12,27 @@ import (
 )
 
 type Feed struct {
-	ID            uuid.UUID
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	LastFetchedAt sql.NullTime
+	ID                     uuid.UUID
+	Name                   string
+	Url                    string
+	UserID                 uuid.UUID
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+	LastFetchedAt          sql.NullTime
+	RefreshIntervalSeconds sql.NullInt32
+	NextFetchAt            sql.NullTime
+	Etag                   sql.NullString
+	LastModified           sql.NullString
+	ErrorCount             int32
+	LastError              sql.NullString
+	LastSuccessAt          sql.NullTime
+	DisabledAt             sql.NullTime
+	GoneAt                 sql.NullTime
+	Title                  sql.NullString
+	Link                   sql.NullString
+	Description            sql.NullString
+	Language               sql.NullString
+	ImageUrl               sql.NullString
 }
 
 type FeedFollow struct {
@@ -27,17 +41,33 @@ type FeedFollow struct {
 	FeedID    uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Folder    sql.NullString
 }
 
 type Post struct {
-	ID          uuid.UUID
-	Title       string
-	Url         string
-	Description string
-	PublishedAt time.Time
-	FeedID      uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID           uuid.UUID
+	Title        string
+	Url          string
+	Description  string
+	PublishedAt  time.Time
+	FeedID       uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Guid         string
+	ContentHash  string
+	RevisedAt    sql.NullTime
+	SearchVector interface{}
+}
+
+type PostState struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	Read      bool
+	ReadAt    sql.NullTime
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Starred   bool
+	StarredAt sql.NullTime
 }
 
 type User struct {
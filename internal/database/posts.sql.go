@@ -7,12 +7,13 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-const createPost = `-- name: CreatePost :exec
+const createPost = `-- name: CreatePost :one
 INSERT INTO posts (
     id,
     title,
@@ -21,9 +22,24 @@ INSERT INTO posts (
     published_at,
     feed_id,
     created_at,
-    updated_at
+    updated_at,
+    guid,
+    content_hash
   )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = EXCLUDED.title,
+  url = EXCLUDED.url,
+  description = EXCLUDED.description,
+  published_at = EXCLUDED.published_at,
+  content_hash = EXCLUDED.content_hash,
+  updated_at = EXCLUDED.updated_at,
+  revised_at = CASE
+    WHEN posts.content_hash = '' THEN posts.revised_at
+    ELSE EXCLUDED.updated_at
+  END
+WHERE posts.content_hash <> EXCLUDED.content_hash
+RETURNING (xmax = 0) AS inserted
 `
 
 type CreatePostParams struct {
@@ -35,10 +51,12 @@ type CreatePostParams struct {
 	FeedID      uuid.UUID
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	Guid        string
+	ContentHash string
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) error {
-	_, err := q.db.ExecContext(ctx, createPost,
+func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, createPost,
 		arg.ID,
 		arg.Title,
 		arg.Url,
@@ -47,33 +65,72 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) error {
 		arg.FeedID,
 		arg.CreatedAt,
 		arg.UpdatedAt,
+		arg.Guid,
+		arg.ContentHash,
 	)
-	return err
+	var inserted bool
+	err := row.Scan(&inserted)
+	return inserted, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT p.id, p.title, p.url, p.description, p.published_at, p.feed_id, p.created_at, p.updated_at
+SELECT p.id,
+  p.title,
+  p.url,
+  p.description,
+  p.published_at,
+  p.feed_id,
+  p.created_at,
+  p.updated_at,
+  p.guid,
+  p.content_hash,
+  p.revised_at,
+  COALESCE(ps.read, FALSE) AS read,
+  COALESCE(ps.starred, FALSE) AS starred
 FROM posts p
   JOIN feed_follows ff ON ff.feed_id = p.feed_id
+  LEFT JOIN post_states ps ON ps.post_id = p.id
+  AND ps.user_id = ff.user_id
 WHERE ff.user_id = $1
+  AND (
+    NOT $2::boolean
+    OR NOT COALESCE(ps.read, FALSE)
+  )
 ORDER BY p.published_at DESC
-LIMIT $2
+LIMIT $3
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID     uuid.UUID
+	UnreadOnly bool
+	PostLimit  int32
+}
+
+type GetPostsForUserRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	Description string
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Guid        string
+	ContentHash string
+	RevisedAt   sql.NullTime
+	Read        bool
+	Starred     bool
 }
 
-func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.UnreadOnly, arg.PostLimit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Post
+	var items []GetPostsForUserRow
 	for rows.Next() {
-		var i Post
+		var i GetPostsForUserRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.Title,
@@ -83,6 +140,11 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.FeedID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Guid,
+			&i.ContentHash,
+			&i.RevisedAt,
+			&i.Read,
+			&i.Starred,
 		); err != nil {
 			return nil, err
 		}
@@ -96,3 +158,144 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const movePosts = `-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = $1
+WHERE feed_id = $2
+  AND guid NOT IN (
+    SELECT guid
+    FROM posts
+    WHERE feed_id = $1
+  )
+`
+
+type MovePostsParams struct {
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MovePosts(ctx context.Context, arg MovePostsParams) error {
+	_, err := q.db.ExecContext(ctx, movePosts, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const searchPostsForUser = `-- name: SearchPostsForUser :many
+SELECT p.id,
+  p.title,
+  p.url,
+  p.published_at,
+  f.name AS feed_name,
+  ts_rank(
+    p.search_vector,
+    websearch_to_tsquery('english', $1::text)
+  ) AS rank,
+  ts_headline(
+    'english',
+    p.description,
+    websearch_to_tsquery('english', $1::text),
+    'StartSel=**, StopSel=**, MaxFragments=2, FragmentDelimiter=" ... "'
+  ) AS snippet
+FROM posts p
+  JOIN feed_follows ff ON ff.feed_id = p.feed_id
+  JOIN feeds f ON f.id = p.feed_id
+WHERE ff.user_id = $2
+  AND p.search_vector @@ websearch_to_tsquery('english', $1::text)
+  AND (
+    $3::uuid IS NULL
+    OR p.feed_id = $3
+  )
+  AND (
+    $4::timestamp IS NULL
+    OR p.published_at >= $4
+  )
+  AND (
+    $5::timestamp IS NULL
+    OR p.published_at < $5
+  )
+ORDER BY rank DESC,
+  p.published_at DESC
+LIMIT $6
+`
+
+type SearchPostsForUserParams struct {
+	Query           string
+	UserID          uuid.UUID
+	FeedID          uuid.NullUUID
+	PublishedAfter  sql.NullTime
+	PublishedBefore sql.NullTime
+	PostLimit       int32
+}
+
+type SearchPostsForUserRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	PublishedAt time.Time
+	FeedName    string
+	Rank        float32
+	Snippet     string
+}
+
+func (q *Queries) SearchPostsForUser(ctx context.Context, arg SearchPostsForUserParams) ([]SearchPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPostsForUser,
+		arg.Query,
+		arg.UserID,
+		arg.FeedID,
+		arg.PublishedAfter,
+		arg.PublishedBefore,
+		arg.PostLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsForUserRow
+	for rows.Next() {
+		var i SearchPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.PublishedAt,
+			&i.FeedName,
+			&i.Rank,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateLegacyPostGUID = `-- name: UpdateLegacyPostGUID :exec
+UPDATE posts
+SET guid = $1
+WHERE feed_id = $2
+  AND guid = $3
+  AND url = $3
+  AND NOT EXISTS (
+    SELECT 1
+    FROM posts existing
+    WHERE existing.feed_id = $2
+      AND existing.guid = $1
+  )
+`
+
+type UpdateLegacyPostGUIDParams struct {
+	Guid   string
+	FeedID uuid.UUID
+	Url    string
+}
+
+func (q *Queries) UpdateLegacyPostGUID(ctx context.Context, arg UpdateLegacyPostGUIDParams) error {
+	_, err := q.db.ExecContext(ctx, updateLegacyPostGUID, arg.Guid, arg.FeedID, arg.Url)
+	return err
+}
@@ -0,0 +1,155 @@
+package feed
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+func init() {
+	Register(atomParser{})
+}
+
+type atomDocument struct {
+	Lang     string      `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Title    atomText    `xml:"title"`
+	Subtitle atomText    `xml:"subtitle"`
+	Logo     string      `xml:"logo"`
+	Icon     string      `xml:"icon"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID        string       `xml:"id"`
+	Title     atomText     `xml:"title"`
+	Links     []atomLink   `xml:"link"`
+	Summary   atomText     `xml:"summary"`
+	Content   atomText     `xml:"content"`
+	Published string       `xml:"published"`
+	Updated   string       `xml:"updated"`
+	Authors   []atomPerson `xml:"author"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+type atomParser struct{}
+
+func (atomParser) Name() string {
+	return "atom"
+}
+
+func (atomParser) Detect(contentType string, body []byte) bool {
+	return hasXMLRoot(body, "feed")
+}
+
+func (atomParser) Parse(body []byte) (*Feed, error) {
+	var doc atomDocument
+	err := unmarshalXML(body, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Feed{
+		Title:       doc.Title.value(),
+		Link:        alternateLink(doc.Links),
+		Description: doc.Subtitle.value(),
+		Language:    doc.Lang,
+		ImageURL:    strings.TrimSpace(doc.Logo),
+	}
+	if f.ImageURL == "" {
+		f.ImageURL = strings.TrimSpace(doc.Icon)
+	}
+	for _, entry := range doc.Entries {
+		description := entry.Summary.value()
+		if description == "" {
+			description = entry.Content.value()
+		}
+		pubDate := entry.Published
+		if pubDate == "" {
+			pubDate = entry.Updated
+		}
+		var authors []string
+		for _, author := range entry.Authors {
+			authors = append(authors, author.Name)
+		}
+		f.Items = append(f.Items, Item{
+			ID:          entry.ID,
+			Title:       entry.Title.value(),
+			Link:        alternateLink(entry.Links),
+			Description: description,
+			PubDate:     pubDate,
+			Author:      strings.Join(authors, ", "),
+		})
+	}
+
+	return f, nil
+}
+
+// alternateLink picks the rel="alternate" link, which is also the default
+// relation when rel is omitted.
+func alternateLink(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+// value returns the text of an Atom text construct. XHTML content keeps its
+// markup, while text and html content are already unescaped by the decoder.
+func (t atomText) value() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(xhtmlContent(t.Inner))
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+// xhtmlContent strips the div that wraps XHTML text constructs. RFC 4287
+// section 3.1.1.3 defines it as a container that is not part of the content.
+func xhtmlContent(inner string) string {
+	decoder := xml.NewDecoder(strings.NewReader(inner))
+	var start int64
+	depth := 0
+	for {
+		offset := decoder.InputOffset()
+		// RawToken leaves namespace prefixes declared outside the fragment,
+		// such as xhtml:div, unresolved instead of failing on them.
+		token, err := decoder.RawToken()
+		if err != nil {
+			return inner
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				if token.Name.Local != "div" {
+					return inner
+				}
+				start = decoder.InputOffset()
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				return inner[start:max(start, offset)]
+			}
+		case xml.CharData:
+			if depth == 0 && strings.TrimSpace(string(token)) != "" {
+				return inner
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package feed
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"golang.org/x/net/html/charset"
+)
+
+// acceptEncoding is sent explicitly, which turns off net/http's transparent
+// gzip handling, so decodeBody must handle every encoding listed here.
+const acceptEncoding = "gzip, deflate, br"
+
+func decodeBody(contentEncoding string, body io.Reader) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "", "identity":
+		return io.NopCloser(body), nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "deflate":
+		return newDeflateReader(body)
+	case "br":
+		return io.NopCloser(brotli.NewReader(body)), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", contentEncoding)
+	}
+}
+
+// newDeflateReader reads the "deflate" content encoding, which is zlib-wrapped
+// DEFLATE. Some servers send raw DEFLATE instead, so a body without a valid
+// zlib header is read as that.
+func newDeflateReader(body io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(body)
+	header, err := buffered.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
+
+var xmlEncodingDecl = regexp.MustCompile(`^\s*<\?xml[^>]*encoding=["']([A-Za-z0-9._:-]+)["']`)
+
+// toUTF8 transcodes an XML body whose charset is only given by the
+// Content-Type header. Documents that declare an encoding themselves are left
+// alone, since the XML decoder's CharsetReader converts them while parsing.
+func toUTF8(contentType string, body []byte) ([]byte, error) {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return body, nil
+	}
+	label := params["charset"]
+	if label == "" || strings.EqualFold(label, "utf-8") || xmlEncodingDecl.Match(body) {
+		return body, nil
+	}
+
+	reader, err := charset.NewReaderLabel(label, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,114 @@
+package feed
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestDecodeBody(t *testing.T) {
+	const want = `<rss version="2.0"><channel><title>Encoded</title></channel></rss>`
+
+	compress := func(newWriter func(io.Writer) io.WriteCloser) []byte {
+		var buf bytes.Buffer
+		w := newWriter(&buf)
+		_, err := io.WriteString(w, want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = w.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		encoding string
+		body     []byte
+	}{
+		{"", []byte(want)},
+		{"identity", []byte(want)},
+		{"gzip", compress(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })},
+		{"x-gzip", compress(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })},
+		{"deflate", compress(func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) })},
+		{"deflate", compress(func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		})},
+		{"br", compress(func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) })},
+		{" GZIP ", compress(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })},
+	}
+	for _, tt := range tests {
+		reader, err := decodeBody(tt.encoding, bytes.NewReader(tt.body))
+		if err != nil {
+			t.Errorf("decodeBody(%q): %v", tt.encoding, err)
+			continue
+		}
+		got, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Errorf("decodeBody(%q): reading: %v", tt.encoding, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("decodeBody(%q) = %q, want %q", tt.encoding, got, want)
+		}
+	}
+}
+
+func TestDecodeBodyUnsupported(t *testing.T) {
+	_, err := decodeBody("compress", bytes.NewReader(nil))
+	if err == nil {
+		t.Fatal("decodeBody(compress) succeeded, want an error")
+	}
+}
+
+func TestToUTF8(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		want        string
+	}{
+		{
+			name:        "no charset",
+			contentType: "application/rss+xml",
+			body:        []byte("<rss>caf\xc3\xa9</rss>"),
+			want:        "<rss>café</rss>",
+		},
+		{
+			name:        "charset from header",
+			contentType: "application/rss+xml; charset=ISO-8859-1",
+			body:        []byte("<rss>caf\xe9</rss>"),
+			want:        "<rss>café</rss>",
+		},
+		{
+			name:        "declaration takes precedence",
+			contentType: "application/rss+xml; charset=ISO-8859-1",
+			body:        []byte(`<?xml version="1.0" encoding="UTF-8"?><rss>caf` + "\xc3\xa9</rss>"),
+			want:        `<?xml version="1.0" encoding="UTF-8"?><rss>café</rss>`,
+		},
+		{
+			name:        "invalid content type",
+			contentType: "not a media type;;",
+			body:        []byte("<rss/>"),
+			want:        "<rss/>",
+		},
+	}
+	for _, tt := range tests {
+		got, err := toUTF8(tt.contentType, tt.body)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
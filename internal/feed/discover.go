@@ -0,0 +1,131 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Candidate is a feed found by Discover.
+type Candidate struct {
+	URL   string
+	Title string
+	// Feed is set when Discover already downloaded the feed, either because
+	// the page was a feed itself or because it was found at a well-known path.
+	Feed *Feed
+}
+
+var feedLinkTypes = []string{
+	"application/rss+xml",
+	"application/atom+xml",
+	"application/rdf+xml",
+	"application/feed+json",
+}
+
+// wellKnownPaths are tried, in order, when a page does not link to any feed.
+var wellKnownPaths = []string{"/feed", "/rss.xml", "/atom.xml"}
+
+// Discover finds the feeds published by the page at pageURL. If pageURL is a
+// feed it is the only candidate. Otherwise the page's <link rel="alternate">
+// elements are used, falling back to the first well-known path on the same
+// site that serves a feed. No candidates and no error means nothing was found.
+func (c *Client) Discover(ctx context.Context, pageURL string) ([]Candidate, error) {
+	resp, err := c.get(ctx, pageURL, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := Parse(resp.contentType, resp.body)
+	if err == nil {
+		feedURL := pageURL
+		if resp.permanentURL != "" {
+			feedURL = resp.permanentURL
+		}
+		return []Candidate{{URL: feedURL, Title: f.Title, Feed: f}}, nil
+	}
+	if !errors.Is(err, ErrUnknownFormat) {
+		return nil, fmt.Errorf("%s is not a valid feed: %w", pageURL, err)
+	}
+
+	base, err := url.Parse(resp.url)
+	if err != nil {
+		return nil, err
+	}
+	if candidates := feedLinks(base, resp.body); len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	for _, path := range wellKnownPaths {
+		candidateURL := base.ResolveReference(&url.URL{Path: path}).String()
+		result, err := c.Fetch(ctx, candidateURL, "", "")
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		return []Candidate{{URL: candidateURL, Title: result.Feed.Title, Feed: result.Feed}}, nil
+	}
+	return nil, nil
+}
+
+// feedLinks returns the feeds an HTML page advertises with
+// <link rel="alternate">, resolving their hrefs against base or the page's
+// own <base> element.
+func feedLinks(base *url.URL, body []byte) []Candidate {
+	var candidates []Candidate
+	seen := make(map[string]bool)
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return candidates
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			if !hasAttr {
+				continue
+			}
+			tag := string(name)
+			if tag != "link" && tag != "base" {
+				continue
+			}
+
+			attrs := make(map[string]string)
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = tokenizer.TagAttr()
+				attrs[string(key)] = string(val)
+			}
+
+			href, err := base.Parse(strings.TrimSpace(attrs["href"]))
+			if err != nil || attrs["href"] == "" {
+				continue
+			}
+			if tag == "base" {
+				base = href
+				continue
+			}
+
+			rel := strings.Fields(strings.ToLower(attrs["rel"]))
+			mediaType, _, _ := mime.ParseMediaType(attrs["type"])
+			if !slices.Contains(rel, "alternate") || !slices.Contains(feedLinkTypes, mediaType) {
+				continue
+			}
+
+			candidateURL := href.String()
+			if seen[candidateURL] {
+				continue
+			}
+			seen[candidateURL] = true
+			candidates = append(candidates, Candidate{URL: candidateURL, Title: strings.TrimSpace(attrs["title"])})
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestFeedLinks(t *testing.T) {
+	body, err := os.ReadFile("testdata/wordpress.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := url.Parse("https://blog.example.com/2024/03/hello-world/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := feedLinks(base, body)
+	want := []Candidate{
+		{URL: "https://blog.example.com/feed/", Title: "Example Blog » Feed"},
+		{URL: "https://blog.example.com/comments/feed/", Title: "Example Blog » Comments Feed"},
+		{URL: "https://cdn.example.com/feeds/atom.xml", Title: "Atom"},
+		{URL: "https://blog.example.com/feed.json"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("feedLinks:\ngot  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestFeedLinksBaseElement(t *testing.T) {
+	body := []byte(`<html><head>
+<base href="https://static.example.com/site/">
+<link rel="alternate" type="application/rss+xml" href="rss.xml">
+</head></html>`)
+	base, _ := url.Parse("https://example.com/")
+
+	got := feedLinks(base, body)
+	want := []Candidate{{URL: "https://static.example.com/site/rss.xml"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("feedLinks = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	rss := []byte(`<rss version="2.0"><channel><title>Example</title></channel></rss>`)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rss.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write(rss)
+	})
+	mux.HandleFunc("/linked", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<link rel="alternate" type="application/atom+xml" href="/atom">`))
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<p>No feed links here.</p>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewClient(ClientOptions{HostDelay: -1})
+	tests := []struct {
+		path     string
+		wantURL  string
+		wantFeed bool
+	}{
+		{"/rss.xml", "/rss.xml", true},
+		{"/linked", "/atom", false},
+		{"/plain", "/rss.xml", true},
+	}
+	for _, tt := range tests {
+		candidates, err := client.Discover(context.Background(), server.URL+tt.path)
+		if err != nil {
+			t.Errorf("Discover(%s): %v", tt.path, err)
+			continue
+		}
+		if len(candidates) != 1 {
+			t.Errorf("Discover(%s) found %d candidates, want 1", tt.path, len(candidates))
+			continue
+		}
+		if got := candidates[0]; got.URL != server.URL+tt.wantURL || (got.Feed != nil) != tt.wantFeed {
+			t.Errorf("Discover(%s) = %+v, want %s", tt.path, got, tt.wantURL)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package feed
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"html"
+	"sync"
+
+	"golang.org/x/net/html/charset"
+)
+
+var ErrUnknownFormat = errors.New("unrecognized feed format")
+
+type Feed struct {
+	Title       string
+	Link        string
+	Description string
+	Language    string
+	ImageURL    string
+	Items       []Item
+	Schedule    Schedule
+}
+
+type Item struct {
+	ID          string
+	Title       string
+	Link        string
+	Description string
+	PubDate     string
+	Author      string
+}
+
+// Parser turns a single feed format into the normalized Feed model. Detect
+// should be cheap, as every registered parser is asked in turn.
+type Parser interface {
+	Name() string
+	Detect(contentType string, body []byte) bool
+	Parse(body []byte) (*Feed, error)
+}
+
+// GUID identifies the item within its feed. Feeds that omit a guid/id get a
+// hash of the link and title, so a re-fetch maps onto the same post.
+func (i Item) GUID() string {
+	if i.ID != "" {
+		return i.ID
+	}
+	sum := sha256.Sum256([]byte(i.Link + "\n" + i.Title))
+	return hex.EncodeToString(sum[:])
+}
+
+// ContentHash changes whenever any stored field of the item changes, which
+// lets the post upsert skip rows that are already up to date.
+func (i Item) ContentHash() string {
+	sum := sha256.Sum256([]byte(i.Title + "\n" + i.Link + "\n" + i.Description + "\n" + i.PubDate))
+	return hex.EncodeToString(sum[:])
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   []Parser
+)
+
+func Register(p Parser) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, p)
+}
+
+func Parsers() []Parser {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return append([]Parser(nil), registry...)
+}
+
+func Parse(contentType string, body []byte) (*Feed, error) {
+	for _, p := range Parsers() {
+		if !p.Detect(contentType, body) {
+			continue
+		}
+		f, err := p.Parse(body)
+		if err != nil {
+			return nil, err
+		}
+		f.unescape()
+		return f, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+func (f *Feed) unescape() {
+	f.Title = html.UnescapeString(f.Title)
+	f.Description = html.UnescapeString(f.Description)
+	for idx, item := range f.Items {
+		f.Items[idx].Title = html.UnescapeString(item.Title)
+		f.Items[idx].Description = html.UnescapeString(item.Description)
+	}
+}
+
+// unmarshalXML is xml.Unmarshal with support for documents declared in
+// encodings other than UTF-8, such as ISO-8859-1, windows-1252 or Shift_JIS.
+func unmarshalXML(body []byte, v any) error {
+	return newXMLDecoder(body).Decode(v)
+}
+
+func newXMLDecoder(body []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = charset.NewReaderLabel
+	return decoder
+}
+
+func xmlRootElement(body []byte) (string, error) {
+	decoder := newXMLDecoder(body)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+func hasXMLRoot(body []byte, name string) bool {
+	root, err := xmlRootElement(body)
+	return err == nil && root == name
+}
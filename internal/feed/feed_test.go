@@ -0,0 +1,251 @@
+package feed
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestParseFixtures(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		contentType string
+		want        *Feed
+	}{
+		{
+			fixture:     "rss.xml",
+			contentType: "application/rss+xml",
+			want: &Feed{
+				Title:       "Example & Co",
+				Link:        "https://example.com/",
+				Description: "News from Example",
+				Language:    "en-us",
+				ImageURL:    "https://example.com/logo.png",
+				Items: []Item{
+					{
+						ID:          "post-1",
+						Title:       `First "post"`,
+						Link:        "https://example.com/posts/1",
+						Description: "<p>Hello <b>world</b></p>",
+						PubDate:     "Mon, 04 Mar 2024 10:00:00 GMT",
+						Author:      "jane@example.com (Jane)",
+					},
+					{
+						Title:       "Second post",
+						Link:        "https://example.com/posts/2",
+						Description: "No guid here",
+						PubDate:     "Tue, 05 Mar 2024 10:00:00 GMT",
+					},
+				},
+				Schedule: Schedule{TTL: 2 * time.Hour},
+			},
+		},
+		{
+			fixture:     "rdf.xml",
+			contentType: "application/rdf+xml",
+			want: &Feed{
+				Title:       "Example RDF",
+				Link:        "https://example.org/",
+				Description: "An RSS 1.0 feed",
+				Language:    "de",
+				ImageURL:    "https://example.org/logo.png",
+				Items: []Item{
+					{
+						ID:          "https://example.org/a",
+						Title:       "Item A",
+						Link:        "https://example.org/a",
+						Description: "About A",
+						PubDate:     "2024-03-04T10:00:00Z",
+						Author:      "Max",
+					},
+					{
+						ID:          "https://example.org/b",
+						Title:       "Item B",
+						Link:        "https://example.org/b",
+						Description: "About B",
+						PubDate:     "2024-03-05T10:00:00Z",
+					},
+				},
+			},
+		},
+		{
+			fixture:     "atom.xml",
+			contentType: "application/atom+xml",
+			want: &Feed{
+				Title:       "Example Atom",
+				Link:        "https://example.net/",
+				Description: "A <em>great</em> feed",
+				Language:    "fr",
+				ImageURL:    "https://example.net/logo.png",
+				Items: []Item{
+					{
+						ID:          "tag:example.net,2024:1",
+						Title:       "Summary entry",
+						Link:        "https://example.net/1",
+						Description: "Just a summary",
+						PubDate:     "2024-03-04T10:00:00Z",
+						Author:      "Ann, Bob",
+					},
+					{
+						ID:          "tag:example.net,2024:2",
+						Title:       "Content <em>entry</em>",
+						Link:        "https://example.net/2",
+						Description: "<p>Full content</p>",
+						PubDate:     "2024-03-05T10:00:00Z",
+					},
+				},
+			},
+		},
+		{
+			fixture:     "feed.json",
+			contentType: "application/feed+json",
+			want: &Feed{
+				Title:       "Example JSON",
+				Link:        "https://example.io/",
+				Description: "A JSON Feed",
+				Language:    "ja",
+				ImageURL:    "https://example.io/favicon.ico",
+				Items: []Item{
+					{
+						ID:          "1",
+						Title:       "Numeric id",
+						Link:        "https://example.io/1",
+						Description: "Summary wins",
+						PubDate:     "2024-03-04T10:00:00Z",
+						Author:      "Ann, Bob",
+					},
+					{
+						ID:          "2",
+						Link:        "https://elsewhere.example/2",
+						Description: "Text only",
+						PubDate:     "2024-03-05T10:00:00Z",
+						Author:      "Legacy Author",
+					},
+					{
+						ID:          "3",
+						Title:       "Inherits feed authors",
+						Link:        "https://example.io/3",
+						Description: "<p>Three</p>",
+						Author:      "Feed Author",
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			got, err := Parse(tt.contentType, readFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse:\ngot  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDetection(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		want        string
+	}{
+		{"rss without content type", "", readFixture(t, "rss.xml"), "Example & Co"},
+		{"rss served as text/xml", "text/xml; charset=utf-8", readFixture(t, "rss.xml"), "Example & Co"},
+		{"rdf served as rss", "application/rss+xml", readFixture(t, "rdf.xml"), "Example RDF"},
+		{"atom served as octet-stream", "application/octet-stream", readFixture(t, "atom.xml"), "Example Atom"},
+		{"json feed without content type", "", readFixture(t, "feed.json"), "Example JSON"},
+		{"json feed served as json", "application/json", readFixture(t, "feed.json"), "Example JSON"},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.contentType, tt.body)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got.Title != tt.want {
+			t.Errorf("%s: parsed as %q, want %q", tt.name, got.Title, tt.want)
+		}
+	}
+}
+
+func TestParseUnknownFormat(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+	}{
+		{"", ""},
+		{"text/html", "<html><body>Not a feed</body></html>"},
+		{"", "<?xml version=\"1.0\"?><sitemap/>"},
+		{"application/json", `{"error":"not found"}`},
+		{"", `{"version":"1.1","title":"Not a JSON Feed"}`},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.contentType, []byte(tt.body))
+		if !errors.Is(err, ErrUnknownFormat) {
+			t.Errorf("Parse(%q, %q) = %v, want ErrUnknownFormat", tt.contentType, tt.body, err)
+		}
+	}
+}
+
+func TestItemGUID(t *testing.T) {
+	withID := Item{ID: "post-1", Title: "Title", Link: "https://example.com/1"}
+	if got := withID.GUID(); got != "post-1" {
+		t.Errorf("GUID() = %q, want the item id", got)
+	}
+
+	withoutID := Item{Title: "Title", Link: "https://example.com/1"}
+	if withoutID.GUID() != withoutID.GUID() || withoutID.GUID() == "" {
+		t.Error("GUID() without an id is not stable")
+	}
+	renamed := withoutID
+	renamed.Title = "Other title"
+	if renamed.GUID() == withoutID.GUID() {
+		t.Error("GUID() without an id ignores the title")
+	}
+}
+
+func TestItemContentHash(t *testing.T) {
+	item := Item{ID: "1", Title: "Title", Link: "https://example.com/1", Description: "Body", PubDate: "today"}
+	edited := item
+	edited.Description = "Edited body"
+	if item.ContentHash() == edited.ContentHash() {
+		t.Error("ContentHash() did not change with the description")
+	}
+	reauthored := item
+	reauthored.Author = "Someone else"
+	if item.ContentHash() != reauthored.ContentHash() {
+		t.Error("ContentHash() changed with a field that is not stored")
+	}
+}
+
+func TestXHTMLContent(t *testing.T) {
+	tests := []struct {
+		inner string
+		want  string
+	}{
+		{`<div xmlns="http://www.w3.org/1999/xhtml">Content <em>entry</em></div>`, "Content <em>entry</em>"},
+		{"\n  <xhtml:div><p>Nested <div>block</div></p></xhtml:div>\n", "<p>Nested <div>block</div></p>"},
+		{`<div xmlns="http://www.w3.org/1999/xhtml"/>`, ""},
+		{"<p>No wrapper</p>", "<p>No wrapper</p>"},
+		{"Plain text", "Plain text"},
+	}
+	for _, tt := range tests {
+		if got := (atomText{Type: "xhtml", Inner: tt.inner}).value(); got != tt.want {
+			t.Errorf("xhtml value of %q = %q, want %q", tt.inner, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	DefaultConnectTimeout  = 10 * time.Second
+	DefaultReadTimeout     = 30 * time.Second
+	DefaultMaxBodySize     = 10 << 20
+	DefaultMaxRedirects    = 5
+	DefaultHostConcurrency = 2
+	DefaultHostDelay       = time.Second
+)
+
+var (
+	ErrBodyTooLarge = errors.New("feed exceeds the maximum body size")
+	ErrGone         = errors.New("feed is gone (410)")
+)
+
+type ClientOptions struct {
+	// ConnectTimeout bounds dialing and the TLS handshake.
+	ConnectTimeout time.Duration
+	// ReadTimeout bounds waiting for the response and reading its body.
+	ReadTimeout  time.Duration
+	MaxBodySize  int64
+	MaxRedirects int
+	UserAgent    string
+	// HostConcurrency limits simultaneous requests to a single host, and
+	// HostDelay is the minimum time between the start of two of them.
+	HostConcurrency int
+	HostDelay       time.Duration
+}
+
+// Client fetches feeds over a single shared transport, so connections to a
+// host are reused across feeds and fetches. It is safe for concurrent use.
+type Client struct {
+	httpClient     *http.Client
+	connectTimeout time.Duration
+	readTimeout    time.Duration
+	maxBodySize    int64
+	userAgent      string
+	hosts          *hostLimiter
+}
+
+type FetchResult struct {
+	Feed         *Feed
+	NotModified  bool
+	ETag         string
+	LastModified string
+	// PermanentURL is set when every redirect on the way to the feed was
+	// permanent (301 or 308), meaning the feed should be fetched from there.
+	PermanentURL string
+}
+
+func NewClient(opts ClientOptions) *Client {
+	if opts.ConnectTimeout <= 0 {
+		opts.ConnectTimeout = DefaultConnectTimeout
+	}
+	if opts.ReadTimeout <= 0 {
+		opts.ReadTimeout = DefaultReadTimeout
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = DefaultMaxBodySize
+	}
+	if opts.MaxRedirects <= 0 {
+		opts.MaxRedirects = DefaultMaxRedirects
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = "gator"
+	}
+	if opts.HostConcurrency <= 0 {
+		opts.HostConcurrency = DefaultHostConcurrency
+	}
+	if opts.HostDelay < 0 {
+		opts.HostDelay = 0
+	} else if opts.HostDelay == 0 {
+		opts.HostDelay = DefaultHostDelay
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   opts.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = opts.ConnectTimeout
+	transport.ResponseHeaderTimeout = opts.ReadTimeout
+	transport.MaxIdleConnsPerHost = opts.HostConcurrency
+	transport.MaxConnsPerHost = opts.HostConcurrency
+
+	maxRedirects := opts.MaxRedirects
+	return &Client{
+		httpClient: &http.Client{
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return fmt.Errorf("stopped after %d redirects", maxRedirects)
+				}
+				return nil
+			},
+		},
+		connectTimeout: opts.ConnectTimeout,
+		readTimeout:    opts.ReadTimeout,
+		maxBodySize:    opts.MaxBodySize,
+		userAgent:      opts.UserAgent,
+		hosts:          newHostLimiter(opts.HostConcurrency, opts.HostDelay),
+	}
+}
+
+// Fetch downloads and parses the feed at feedURL. The etag and lastModified
+// validators of a previous fetch, if any, make the request conditional.
+func (c *Client) Fetch(ctx context.Context, feedURL, etag, lastModified string) (FetchResult, error) {
+	resp, err := c.get(ctx, feedURL, etag, lastModified)
+	if err != nil {
+		return FetchResult{}, err
+	}
+	if resp.notModified {
+		return FetchResult{NotModified: true, ETag: etag, LastModified: lastModified, PermanentURL: resp.permanentURL}, nil
+	}
+
+	f, err := Parse(resp.contentType, resp.body)
+	if err != nil {
+		return FetchResult{}, err
+	}
+
+	return FetchResult{
+		Feed:         f,
+		ETag:         resp.etag,
+		LastModified: resp.lastModified,
+		PermanentURL: resp.permanentURL,
+	}, nil
+}
+
+// MaxFetchTime is the longest a single Fetch can take: waiting out a short
+// host pause plus the request timeout.
+func (c *Client) MaxFetchTime() time.Duration {
+	return maxPauseWait + c.connectTimeout + c.readTimeout
+}
+
+type response struct {
+	// url is where the body was fetched from after following redirects.
+	url          string
+	contentType  string
+	etag         string
+	lastModified string
+	permanentURL string
+	notModified  bool
+	body         []byte
+}
+
+func (c *Client) get(ctx context.Context, rawURL, etag, lastModified string) (response, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return response{}, err
+	}
+	host := parsedURL.Host
+
+	// Waiting for the host is not part of the request timeout.
+	release, err := c.hosts.acquire(ctx, host)
+	if err != nil {
+		return response{}, err
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(ctx, c.connectTimeout+c.readTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return response{}, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, */*;q=0.8")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return response{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		delay := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		c.hosts.pause(host, time.Now().Add(delay), resp.Status)
+		return response{}, &RetryAfterError{Status: resp.Status, Delay: delay}
+	}
+
+	if resp.StatusCode == http.StatusGone {
+		return response{}, ErrGone
+	}
+	result := response{
+		url:          resp.Request.URL.String(),
+		contentType:  resp.Header.Get("Content-Type"),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		permanentURL: permanentRedirect(resp),
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		result.notModified = true
+		return result, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return response{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, err := c.readBody(resp)
+	if err != nil {
+		return response{}, err
+	}
+
+	result.body, err = toUTF8(result.contentType, body)
+	if err != nil {
+		return response{}, err
+	}
+	return result, nil
+}
+
+// permanentRedirect walks the redirect chain that led to resp and returns the
+// final URL if every hop was permanent.
+func permanentRedirect(resp *http.Response) string {
+	final := resp.Request
+	if final == nil || final.Response == nil {
+		return ""
+	}
+	for req := final; req.Response != nil; req = req.Response.Request {
+		code := req.Response.StatusCode
+		if code != http.StatusMovedPermanently && code != http.StatusPermanentRedirect {
+			return ""
+		}
+	}
+	return final.URL.String()
+}
+
+func (c *Client) readBody(resp *http.Response) ([]byte, error) {
+	if resp.ContentLength > c.maxBodySize {
+		return nil, fmt.Errorf("%w (%d bytes)", ErrBodyTooLarge, c.maxBodySize)
+	}
+	decoded, err := decodeBody(resp.Header.Get("Content-Encoding"), resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	defer decoded.Close()
+
+	// The limit applies to the decompressed size, so a small compressed
+	// response cannot expand into an unbounded amount of memory.
+	body, err := io.ReadAll(io.LimitReader(decoded, c.maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > c.maxBodySize {
+		return nil, fmt.Errorf("%w (%d bytes)", ErrBodyTooLarge, c.maxBodySize)
+	}
+	return body, nil
+}
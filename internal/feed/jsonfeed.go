@@ -0,0 +1,150 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"strings"
+)
+
+func init() {
+	Register(jsonFeedParser{})
+}
+
+const jsonFeedVersionPrefix = "https://jsonfeed.org/version/"
+
+type jsonFeedDocument struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	Description string           `json:"description"`
+	Language    string           `json:"language"`
+	Icon        string           `json:"icon"`
+	Favicon     string           `json:"favicon"`
+	Items       []jsonFeedItem   `json:"items"`
+	Authors     []jsonFeedAuthor `json:"authors"`
+}
+
+type jsonFeedItem struct {
+	ID            jsonFeedID       `json:"id"`
+	URL           string           `json:"url"`
+	ExternalURL   string           `json:"external_url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	ContentText   string           `json:"content_text"`
+	Summary       string           `json:"summary"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Authors       []jsonFeedAuthor `json:"authors"`
+	Author        *jsonFeedAuthor  `json:"author"`
+}
+
+// jsonFeedID is an item id. The spec requires a string but asks readers to
+// accept numbers too, coercing them to strings.
+type jsonFeedID string
+
+func (id *jsonFeedID) UnmarshalJSON(data []byte) error {
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err == nil {
+		*id = jsonFeedID(number.String())
+		return nil
+	}
+	var value *string
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+	if value != nil {
+		*id = jsonFeedID(*value)
+	}
+	return nil
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type jsonFeedParser struct{}
+
+func (jsonFeedParser) Name() string {
+	return "jsonfeed"
+}
+
+func (jsonFeedParser) Detect(contentType string, body []byte) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		switch mediaType {
+		case "application/feed+json", "application/json":
+			return true
+		case "application/rss+xml", "application/atom+xml", "application/rdf+xml":
+			return false
+		}
+	}
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+func (jsonFeedParser) Parse(body []byte) (*Feed, error) {
+	var doc jsonFeedDocument
+	err := json.Unmarshal(body, &doc)
+	if err != nil {
+		return nil, err
+	}
+	// Detection accepts any JSON object, so the version is what tells a feed
+	// apart from an arbitrary API response.
+	if !strings.HasPrefix(doc.Version, jsonFeedVersionPrefix) {
+		return nil, ErrUnknownFormat
+	}
+
+	f := &Feed{
+		Title:       doc.Title,
+		Link:        doc.HomePageURL,
+		Description: doc.Description,
+		Language:    doc.Language,
+		ImageURL:    doc.Icon,
+	}
+	if f.ImageURL == "" {
+		f.ImageURL = doc.Favicon
+	}
+	for _, item := range doc.Items {
+		description := item.Summary
+		if description == "" {
+			description = item.ContentHTML
+		}
+		if description == "" {
+			description = item.ContentText
+		}
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+		pubDate := item.DatePublished
+		if pubDate == "" {
+			pubDate = item.DateModified
+		}
+		// JSON Feed 1.0 used a single author object; 1.1 replaced it with
+		// authors, and items inherit the feed-level authors when they have none.
+		authors := item.Authors
+		if len(authors) == 0 && item.Author != nil {
+			authors = []jsonFeedAuthor{*item.Author}
+		}
+		if len(authors) == 0 {
+			authors = doc.Authors
+		}
+		var authorNames []string
+		for _, author := range authors {
+			authorNames = append(authorNames, author.Name)
+		}
+		f.Items = append(f.Items, Item{
+			ID:          string(item.ID),
+			Title:       item.Title,
+			Link:        link,
+			Description: description,
+			PubDate:     pubDate,
+			Author:      strings.Join(authorNames, ", "),
+		})
+	}
+
+	return f, nil
+}
@@ -0,0 +1,135 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryAfterError is returned when a host answers 429 Too Many Requests or
+// 503 Service Unavailable. Delay is zero when no usable Retry-After header
+// was sent.
+type RetryAfterError struct {
+	Status string
+	Delay  time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	if e.Delay <= 0 {
+		return fmt.Sprintf("unexpected status %s", e.Status)
+	}
+	return fmt.Sprintf("unexpected status %s, retry after %s", e.Status, e.Delay)
+}
+
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
+
+// maxPauseWait is the longest acquire waits for a paused host. Beyond that it
+// fails with a RetryAfterError, so the feed is rescheduled instead of holding
+// up a worker.
+const maxPauseWait = 30 * time.Second
+
+// hostLimiter caps the number of concurrent requests to a host and spaces
+// the start of consecutive requests by at least minDelay.
+type hostLimiter struct {
+	mu            sync.Mutex
+	hosts         map[string]*hostState
+	maxConcurrent int
+	minDelay      time.Duration
+}
+
+type hostState struct {
+	slots       chan struct{}
+	mu          sync.Mutex
+	next        time.Time
+	pausedUntil time.Time
+	pauseStatus string
+}
+
+func newHostLimiter(maxConcurrent int, minDelay time.Duration) *hostLimiter {
+	return &hostLimiter{
+		hosts:         make(map[string]*hostState),
+		maxConcurrent: maxConcurrent,
+		minDelay:      minDelay,
+	}
+}
+
+func (l *hostLimiter) host(name string) *hostState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h, ok := l.hosts[name]
+	if !ok {
+		h = &hostState{slots: make(chan struct{}, l.maxConcurrent)}
+		l.hosts[name] = h
+	}
+	return h
+}
+
+// acquire blocks until a request to host may start. The returned function
+// must be called once the request is done.
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	h := l.host(host)
+	select {
+	case h.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-h.slots }
+
+	h.mu.Lock()
+	now := time.Now()
+	if paused := h.pausedUntil.Sub(now); paused > maxPauseWait {
+		status := h.pauseStatus
+		h.mu.Unlock()
+		release()
+		return nil, &RetryAfterError{Status: status, Delay: paused}
+	}
+	start := now
+	if h.next.After(start) {
+		start = h.next
+	}
+	if h.pausedUntil.After(start) {
+		start = h.pausedUntil
+	}
+	h.next = start.Add(l.minDelay)
+	h.mu.Unlock()
+
+	if wait := start.Sub(now); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// pause keeps new requests away from host until the given time, used when
+// the host asks us to back off with the given response status.
+func (l *hostLimiter) pause(host string, until time.Time, status string) {
+	h := l.host(host)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if until.After(h.pausedUntil) {
+		h.pausedUntil = until
+		h.pauseStatus = status
+	}
+}
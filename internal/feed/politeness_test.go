@@ -0,0 +1,62 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{" 5 ", 5 * time.Second},
+		{"-5", 0},
+		{"Fri, 01 Mar 2024 12:10:00 GMT", 10 * time.Minute},
+		{"Fri, 01 Mar 2024 11:00:00 GMT", 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestHostLimiterLongPause(t *testing.T) {
+	limiter := newHostLimiter(2, 0)
+	limiter.pause("example.com", time.Now().Add(24*time.Hour), "429 Too Many Requests")
+
+	_, err := limiter.acquire(context.Background(), "example.com")
+	var retryErr *RetryAfterError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("acquire on a paused host = %v, want a RetryAfterError", err)
+	}
+	if retryErr.Delay <= 23*time.Hour || retryErr.Status != "429 Too Many Requests" {
+		t.Errorf("got %+v, want the remaining pause and the original status", retryErr)
+	}
+
+	if len(limiter.host("example.com").slots) != 0 {
+		t.Error("failed acquire kept a slot")
+	}
+}
+
+func TestHostLimiterShortPause(t *testing.T) {
+	limiter := newHostLimiter(1, 0)
+	limiter.pause("example.com", time.Now().Add(50*time.Millisecond), "503 Service Unavailable")
+
+	start := time.Now()
+	release, err := limiter.acquire(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if waited := time.Since(start); waited < 40*time.Millisecond {
+		t.Errorf("acquire returned after %s, want it to wait out the pause", waited)
+	}
+}
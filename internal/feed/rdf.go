@@ -0,0 +1,71 @@
+package feed
+
+func init() {
+	Register(rdfParser{})
+}
+
+type rdfDocument struct {
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		Language    string `xml:"http://purl.org/dc/elements/1.1/ language"`
+		scheduleHints
+	} `xml:"channel"`
+	Image struct {
+		URL string `xml:"url"`
+	} `xml:"image"`
+	Items []rdfItem `xml:"item"`
+}
+
+type rdfItem struct {
+	About       string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+}
+
+type rdfParser struct{}
+
+func (rdfParser) Name() string {
+	return "rdf"
+}
+
+func (rdfParser) Detect(contentType string, body []byte) bool {
+	return hasXMLRoot(body, "RDF")
+}
+
+func (rdfParser) Parse(body []byte) (*Feed, error) {
+	var doc rdfDocument
+	err := unmarshalXML(body, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Feed{
+		Title:       doc.Channel.Title,
+		Link:        doc.Channel.Link,
+		Description: doc.Channel.Description,
+		Language:    doc.Channel.Language,
+		ImageURL:    doc.Image.URL,
+		Schedule:    doc.Channel.schedule(),
+	}
+	for _, item := range doc.Items {
+		id := item.About
+		if id == "" {
+			id = item.Link
+		}
+		f.Items = append(f.Items, Item{
+			ID:          id,
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			PubDate:     item.Date,
+			Author:      item.Creator,
+		})
+	}
+
+	return f, nil
+}
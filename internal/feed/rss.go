@@ -0,0 +1,67 @@
+package feed
+
+func init() {
+	Register(rssParser{})
+}
+
+type rssDocument struct {
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		Language    string `xml:"language"`
+		Image       struct {
+			URL string `xml:"url"`
+		} `xml:"image"`
+		Item []rssItem `xml:"item"`
+		scheduleHints
+	} `xml:"channel"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+	Guid        string `xml:"guid"`
+	Author      string `xml:"author"`
+}
+
+type rssParser struct{}
+
+func (rssParser) Name() string {
+	return "rss"
+}
+
+func (rssParser) Detect(contentType string, body []byte) bool {
+	return hasXMLRoot(body, "rss")
+}
+
+func (rssParser) Parse(body []byte) (*Feed, error) {
+	var doc rssDocument
+	err := unmarshalXML(body, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Feed{
+		Title:       doc.Channel.Title,
+		Link:        doc.Channel.Link,
+		Description: doc.Channel.Description,
+		Language:    doc.Channel.Language,
+		ImageURL:    doc.Channel.Image.URL,
+		Schedule:    doc.Channel.schedule(),
+	}
+	for _, item := range doc.Channel.Item {
+		f.Items = append(f.Items, Item{
+			ID:          item.Guid,
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			PubDate:     item.PubDate,
+			Author:      item.Author,
+		})
+	}
+
+	return f, nil
+}
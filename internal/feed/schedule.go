@@ -0,0 +1,125 @@
+package feed
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxInterval caps publisher hints, so a yearly sy:updatePeriod does not hide
+// a feed for months.
+const maxInterval = 24 * time.Hour
+
+// Schedule holds the publisher's hints about how often a feed changes.
+type Schedule struct {
+	// TTL is the minimum time the feed may be cached before refreshing.
+	TTL       time.Duration
+	SkipHours []int
+	SkipDays  []time.Weekday
+}
+
+// Interval returns how long to wait between fetches: the fallback, unless the
+// publisher asked to be polled less often.
+func (sch Schedule) Interval(fallback time.Duration) time.Duration {
+	if sch.TTL <= fallback {
+		return fallback
+	}
+	return min(sch.TTL, max(fallback, maxInterval))
+}
+
+// NextFetch returns the earliest time after from+interval that is not in one
+// of the feed's skipHours or skipDays, which are expressed in GMT.
+func (sch Schedule) NextFetch(from time.Time, interval time.Duration) time.Time {
+	next := from.Add(interval)
+	for range 24 * 7 {
+		if !sch.skipped(next) {
+			return next
+		}
+		next = next.UTC().Truncate(time.Hour).Add(time.Hour)
+	}
+	return next
+}
+
+func (sch Schedule) skipped(t time.Time) bool {
+	t = t.UTC()
+	for _, hour := range sch.SkipHours {
+		if t.Hour() == hour {
+			return true
+		}
+	}
+	for _, day := range sch.SkipDays {
+		if t.Weekday() == day {
+			return true
+		}
+	}
+	return false
+}
+
+type scheduleHints struct {
+	TTL             string   `xml:"ttl"`
+	SkipHours       []string `xml:"skipHours>hour"`
+	SkipDays        []string `xml:"skipDays>day"`
+	UpdatePeriod    string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	UpdateFrequency string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+}
+
+func (h scheduleHints) schedule() Schedule {
+	var sch Schedule
+
+	if minutes, err := strconv.Atoi(strings.TrimSpace(h.TTL)); err == nil && minutes > 0 {
+		sch.TTL = time.Duration(minutes) * time.Minute
+	}
+
+	if period := syndicationPeriod(h.UpdatePeriod, h.UpdateFrequency); period > sch.TTL {
+		sch.TTL = period
+	}
+
+	for _, hour := range h.SkipHours {
+		// RSS 2.0 allows 0-23, but some feeds use 24 for midnight.
+		if value, err := strconv.Atoi(strings.TrimSpace(hour)); err == nil && value >= 0 && value <= 24 {
+			sch.SkipHours = append(sch.SkipHours, value%24)
+		}
+	}
+
+	for _, day := range h.SkipDays {
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			if strings.EqualFold(strings.TrimSpace(day), weekday.String()) {
+				sch.SkipDays = append(sch.SkipDays, weekday)
+			}
+		}
+	}
+
+	// A feed that skips every hour or every day would never be fetched again.
+	if len(sch.SkipHours) >= 24 {
+		sch.SkipHours = nil
+	}
+	if len(sch.SkipDays) >= 7 {
+		sch.SkipDays = nil
+	}
+
+	return sch
+}
+
+func syndicationPeriod(period, frequency string) time.Duration {
+	var base time.Duration
+	switch strings.TrimSpace(period) {
+	case "hourly":
+		base = time.Hour
+	case "daily":
+		base = 24 * time.Hour
+	case "weekly":
+		base = 7 * 24 * time.Hour
+	case "monthly":
+		base = 30 * 24 * time.Hour
+	case "yearly":
+		base = 365 * 24 * time.Hour
+	default:
+		return 0
+	}
+
+	times := 1
+	if value, err := strconv.Atoi(strings.TrimSpace(frequency)); err == nil && value > 0 {
+		times = value
+	}
+	return base / time.Duration(times)
+}
@@ -0,0 +1,121 @@
+package feed
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScheduleInterval(t *testing.T) {
+	tests := []struct {
+		ttl      time.Duration
+		fallback time.Duration
+		want     time.Duration
+	}{
+		{0, time.Hour, time.Hour},
+		{30 * time.Minute, time.Hour, time.Hour},
+		{2 * time.Hour, time.Hour, 2 * time.Hour},
+		{7 * 24 * time.Hour, time.Hour, maxInterval},
+		{7 * 24 * time.Hour, 48 * time.Hour, 48 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := (Schedule{TTL: tt.ttl}).Interval(tt.fallback); got != tt.want {
+			t.Errorf("Schedule{TTL: %s}.Interval(%s) = %s, want %s", tt.ttl, tt.fallback, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleNextFetch(t *testing.T) {
+	// 2024-03-01 is a Friday.
+	from := time.Date(2024, time.March, 1, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		schedule Schedule
+		from     time.Time
+		interval time.Duration
+		want     time.Time
+	}{
+		{
+			name:     "no hints",
+			from:     from,
+			interval: time.Hour,
+			want:     time.Date(2024, time.March, 1, 11, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "skipped hours",
+			schedule: Schedule{SkipHours: []int{11, 12}},
+			from:     from,
+			interval: time.Hour,
+			want:     time.Date(2024, time.March, 1, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "skipped day",
+			schedule: Schedule{SkipDays: []time.Weekday{time.Saturday}},
+			from:     time.Date(2024, time.March, 1, 23, 30, 0, 0, time.UTC),
+			interval: time.Hour,
+			want:     time.Date(2024, time.March, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "hours are in GMT",
+			schedule: Schedule{SkipHours: []int{11}},
+			from:     time.Date(2024, time.March, 1, 13, 30, 0, 0, time.FixedZone("CEST", 2*60*60)),
+			want:     time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.schedule.NextFetch(tt.from, tt.interval); !got.Equal(tt.want) {
+			t.Errorf("%s: NextFetch = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleHints(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel string
+		want    Schedule
+	}{
+		{
+			name:    "ttl",
+			channel: `<ttl> 90 </ttl>`,
+			want:    Schedule{TTL: 90 * time.Minute},
+		},
+		{
+			name:    "invalid ttl",
+			channel: `<ttl>-5</ttl>`,
+			want:    Schedule{},
+		},
+		{
+			name:    "syndication period wins over a shorter ttl",
+			channel: `<ttl>60</ttl><sy:updatePeriod>daily</sy:updatePeriod><sy:updateFrequency>2</sy:updateFrequency>`,
+			want:    Schedule{TTL: 12 * time.Hour},
+		},
+		{
+			name:    "syndication period without frequency",
+			channel: `<sy:updatePeriod> weekly </sy:updatePeriod>`,
+			want:    Schedule{TTL: 7 * 24 * time.Hour},
+		},
+		{
+			name:    "skip hours and days",
+			channel: `<skipHours><hour>24</hour><hour>1</hour><hour>25</hour><hour>noon</hour></skipHours><skipDays><day>monday</day><day> Sunday </day><day>Funday</day></skipDays>`,
+			want:    Schedule{SkipHours: []int{0, 1}, SkipDays: []time.Weekday{time.Monday, time.Sunday}},
+		},
+		{
+			name:    "every day skipped",
+			channel: `<skipDays><day>Monday</day><day>Tuesday</day><day>Wednesday</day><day>Thursday</day><day>Friday</day><day>Saturday</day><day>Sunday</day></skipDays>`,
+			want:    Schedule{},
+		},
+	}
+	for _, tt := range tests {
+		var hints scheduleHints
+		err := xml.Unmarshal([]byte(`<channel xmlns:sy="http://purl.org/rss/1.0/modules/syndication/">`+tt.channel+`</channel>`), &hints)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := hints.schedule(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: schedule() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
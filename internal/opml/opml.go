@@ -0,0 +1,143 @@
+// Package opml reads and writes OPML 2.0 subscription lists, the format feed
+// readers use to exchange the feeds a user follows.
+package opml
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+type Document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+// Outline is either a feed, when XMLURL is set, or a folder of further
+// outlines.
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// Subscription is a feed outline together with the folder it was nested in.
+type Subscription struct {
+	Title   string
+	URL     string
+	SiteURL string
+	// Folder is the path of the enclosing folders joined with "/", or empty
+	// for feeds at the top level.
+	Folder string
+}
+
+func Parse(r io.Reader) (*Document, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReaderLabel
+
+	var doc Document
+	err := decoder.Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Subscriptions flattens the outline tree into the feeds it contains. Leaf
+// outlines without an xmlUrl are returned too, with an empty URL, so callers
+// can report them rather than silently dropping them.
+func (d *Document) Subscriptions() []Subscription {
+	var subscriptions []Subscription
+	var walk func(outlines []Outline, folder string)
+	walk = func(outlines []Outline, folder string) {
+		for _, outline := range outlines {
+			if outline.XMLURL != "" || len(outline.Outlines) == 0 {
+				subscriptions = append(subscriptions, Subscription{
+					Title:   outline.label(),
+					URL:     strings.TrimSpace(outline.XMLURL),
+					SiteURL: strings.TrimSpace(outline.HTMLURL),
+					Folder:  folder,
+				})
+			}
+			if len(outline.Outlines) > 0 {
+				subfolder := outline.label()
+				if folder != "" {
+					subfolder = folder + "/" + subfolder
+				}
+				walk(outline.Outlines, subfolder)
+			}
+		}
+	}
+	walk(d.Body.Outlines, "")
+	return subscriptions
+}
+
+// New builds an OPML 2.0 document from subscriptions, nesting each one in
+// outlines for its folder path.
+func New(title string, subscriptions []Subscription) *Document {
+	doc := &Document{Version: "2.0", Head: Head{Title: title}}
+	for _, subscription := range subscriptions {
+		outlines := &doc.Body.Outlines
+		if subscription.Folder != "" {
+			for _, name := range strings.Split(subscription.Folder, "/") {
+				outlines = &folder(outlines, name).Outlines
+			}
+		}
+		*outlines = append(*outlines, Outline{
+			Text:    subscription.Title,
+			Title:   subscription.Title,
+			Type:    "rss",
+			XMLURL:  subscription.URL,
+			HTMLURL: subscription.SiteURL,
+		})
+	}
+	return doc
+}
+
+func folder(outlines *[]Outline, name string) *Outline {
+	for i := range *outlines {
+		if (*outlines)[i].XMLURL == "" && (*outlines)[i].Text == name {
+			return &(*outlines)[i]
+		}
+	}
+	*outlines = append(*outlines, Outline{Text: name})
+	return &(*outlines)[len(*outlines)-1]
+}
+
+func (d *Document) Write(w io.Writer) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(d)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func (o Outline) label() string {
+	if title := strings.TrimSpace(o.Title); title != "" {
+		return title
+	}
+	return strings.TrimSpace(o.Text)
+}
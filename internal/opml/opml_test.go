@@ -0,0 +1,93 @@
+package opml
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSubscriptions(t *testing.T) {
+	file, err := os.Open("testdata/subscriptions.opml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	doc, err := Parse(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Head.Title != "Reader subscriptions" {
+		t.Errorf("Head.Title = %q, want %q", doc.Head.Title, "Reader subscriptions")
+	}
+
+	got := doc.Subscriptions()
+	want := []Subscription{
+		{Title: "Top level", URL: "https://example.com/feed.xml", SiteURL: "https://example.com/"},
+		{Title: "Go Blog", URL: "https://go.dev/blog/feed.atom", SiteURL: "https://go.dev/blog", Folder: "Tech"},
+		{Title: "Postgres News", URL: "https://www.postgresql.org/news.rss", Folder: "Tech/Databases"},
+		{Title: "Bookmark without a feed", SiteURL: "https://example.org/", Folder: "Tech"},
+		{Title: "Example News", URL: "https://news.example.com/rss", Folder: "News"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Subscriptions:\ngot  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseCharset(t *testing.T) {
+	input := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n" +
+		"<opml version=\"2.0\"><body><outline text=\"Caf\xe9\" xmlUrl=\"https://example.com/rss\"/></body></opml>"
+	doc, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriptions := doc.Subscriptions()
+	if len(subscriptions) != 1 || subscriptions[0].Title != "Café" {
+		t.Errorf("Subscriptions = %+v, want a single feed titled %q", subscriptions, "Café")
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"<opml><body><outline text=\"unclosed\"></body></opml>",
+		"<rss version=\"2.0\"><channel/></rss>",
+	} {
+		_, err := Parse(strings.NewReader(input))
+		if err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", input)
+		}
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	subscriptions := []Subscription{
+		{Title: "Top level", URL: "https://example.com/feed.xml", SiteURL: "https://example.com/"},
+		{Title: "Go Blog", URL: "https://go.dev/blog/feed.atom", Folder: "Tech"},
+		{Title: "Postgres News", URL: "https://www.postgresql.org/news.rss", Folder: "Tech/Databases"},
+		{Title: "Rust Blog", URL: "https://blog.rust-lang.org/feed.xml", Folder: "Tech"},
+		{Title: "Tom & Jerry <News>", URL: "https://news.example.com/rss?a=1&b=2", Folder: "News"},
+	}
+
+	var buf strings.Builder
+	err := New("gator subscriptions", subscriptions).Write(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), `<?xml version="1.0" encoding="UTF-8"?>`) {
+		t.Errorf("Write output does not start with an XML declaration:\n%s", buf.String())
+	}
+
+	doc, err := Parse(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("parsing written document: %v\n%s", err, buf.String())
+	}
+	if doc.Version != "2.0" || doc.Head.Title != "gator subscriptions" {
+		t.Errorf("got version %q and title %q, want 2.0 and %q", doc.Version, doc.Head.Title, "gator subscriptions")
+	}
+
+	if got := doc.Subscriptions(); !reflect.DeepEqual(got, subscriptions) {
+		t.Errorf("Subscriptions after round trip:\ngot  %+v\nwant %+v", got, subscriptions)
+	}
+}
@@ -1,33 +1,38 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
-	"encoding/xml"
+	"errors"
+	"flag"
 	"fmt"
-	"html"
-	"io"
 	"log"
-	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/max-programming/gator/internal/config"
 	"github.com/max-programming/gator/internal/database"
+	"github.com/max-programming/gator/internal/feed"
+	"github.com/max-programming/gator/internal/opml"
 
 	"github.com/google/uuid"
-	pq "github.com/lib/pq"
+	_ "github.com/lib/pq"
 	"github.com/timematic/anytime"
 )
 
-const (
-	UniqueViolationError = pq.ErrorCode("23505")
-)
-
 type state struct {
-	db  *database.Queries
-	cfg *config.Config
+	db     *database.Queries
+	sqlDB  *sql.DB
+	cfg    *config.Config
+	client *feed.Client
 }
 
 type command struct {
@@ -36,23 +41,7 @@ type command struct {
 }
 
 type commands struct {
-	cmds map[string]func(*state, command) error
-}
-
-type RSSFeed struct {
-	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
-	} `xml:"channel"`
-}
-
-type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	cmds map[string]func(context.Context, *state, command) error
 }
 
 func main() {
@@ -69,24 +58,42 @@ func main() {
 	dbQueries := database.New(db)
 
 	s := state{
-		db:  dbQueries,
-		cfg: &cfg,
+		db:    dbQueries,
+		sqlDB: db,
+		cfg:   &cfg,
+		client: feed.NewClient(feed.ClientOptions{
+			ConnectTimeout:  time.Duration(cfg.Fetch.ConnectTimeout),
+			ReadTimeout:     time.Duration(cfg.Fetch.ReadTimeout),
+			MaxBodySize:     cfg.Fetch.MaxBodyBytes,
+			MaxRedirects:    cfg.Fetch.MaxRedirects,
+			HostConcurrency: cfg.Fetch.HostConcurrency,
+			HostDelay:       time.Duration(cfg.Fetch.HostDelay),
+		}),
 	}
 	cmds := commands{
-		make(map[string]func(*state, command) error),
+		make(map[string]func(context.Context, *state, command) error),
 	}
 
 	cmds.register("login", handlerLogin)
 	cmds.register("register", handleRegister)
 	cmds.register("reset", handleReset)
 	cmds.register("users", handleUsers)
-	cmds.register("agg", middlewareLoggedIn(handleAgg))
+	cmds.register("agg", handleAgg)
+	cmds.register("fetch", handleFetch)
 	cmds.register("addfeed", middlewareLoggedIn(handleAddFeed))
 	cmds.register("feeds", handleFeeds)
 	cmds.register("follow", middlewareLoggedIn(handleFollow))
 	cmds.register("following", middlewareLoggedIn(handleFollowing))
 	cmds.register("unfollow", middlewareLoggedIn(handleUnfollow))
+	cmds.register("import", middlewareLoggedIn(handleImport))
+	cmds.register("export", middlewareLoggedIn(handleExport))
 	cmds.register("browse", middlewareLoggedIn(handleBrowse))
+	cmds.register("read", middlewareLoggedIn(handleRead))
+	cmds.register("unread", middlewareLoggedIn(handleUnread))
+	cmds.register("star", middlewareLoggedIn(handleStar))
+	cmds.register("unstar", middlewareLoggedIn(handleUnstar))
+	cmds.register("starred", middlewareLoggedIn(handleStarred))
+	cmds.register("search", middlewareLoggedIn(handleSearch))
 
 	args := os.Args
 	if len(args) < 2 {
@@ -96,13 +103,22 @@ func main() {
 	cmdName := args[1]
 	cmd := command{name: cmdName, args: args[2:]}
 
-	err = cmds.run(&s, cmd)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Restore the default signal handling once the first signal arrives, so
+	// a second Ctrl-C kills a process that is taking too long to shut down.
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err = cmds.run(ctx, &s, cmd)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
-func handlerLogin(s *state, cmd command) error {
+func handlerLogin(ctx context.Context, s *state, cmd command) error {
 	if cmd.name != "login" {
 		return fmt.Errorf("invalid command")
 	}
@@ -110,7 +126,7 @@ func handlerLogin(s *state, cmd command) error {
 		return fmt.Errorf("username is required")
 	}
 	username := cmd.args[0]
-	user, err := s.db.GetUser(context.Background(), username)
+	user, err := s.db.GetUser(ctx, username)
 	if err != nil {
 		return err
 	}
@@ -122,7 +138,7 @@ func handlerLogin(s *state, cmd command) error {
 	return nil
 }
 
-func handleRegister(s *state, cmd command) error {
+func handleRegister(ctx context.Context, s *state, cmd command) error {
 	if cmd.name != "register" {
 		return fmt.Errorf("invalid command")
 	}
@@ -131,7 +147,7 @@ func handleRegister(s *state, cmd command) error {
 	}
 	username := cmd.args[0]
 	user, err := s.db.CreateUser(
-		context.Background(),
+		ctx,
 		database.CreateUserParams{
 			ID:        uuid.New(),
 			Name:      username,
@@ -153,11 +169,11 @@ func handleRegister(s *state, cmd command) error {
 	return nil
 }
 
-func handleReset(s *state, cmd command) error {
+func handleReset(ctx context.Context, s *state, cmd command) error {
 	if cmd.name != "reset" {
 		return fmt.Errorf("invalid command")
 	}
-	err := s.db.DeleteUsers(context.Background())
+	err := s.db.DeleteUsers(ctx)
 	if err != nil {
 		return err
 	}
@@ -165,11 +181,11 @@ func handleReset(s *state, cmd command) error {
 	return nil
 }
 
-func handleUsers(s *state, cmd command) error {
+func handleUsers(ctx context.Context, s *state, cmd command) error {
 	if cmd.name != "users" {
 		return fmt.Errorf("invalid command")
 	}
-	users, err := s.db.GetUsers(context.Background())
+	users, err := s.db.GetUsers(ctx)
 	if err != nil {
 		return err
 	}
@@ -183,47 +199,229 @@ func handleUsers(s *state, cmd command) error {
 	return nil
 }
 
-func handleAgg(s *state, cmd command, user database.User) error {
+func handleAgg(ctx context.Context, s *state, cmd command) error {
 	if cmd.name != "agg" {
 		return fmt.Errorf("invalid command")
 	}
-	if len(cmd.args) < 1 {
+
+	fs := flag.NewFlagSet("agg", flag.ContinueOnError)
+	workers := fs.Int("workers", 4, "number of feeds fetched concurrently")
+	batchSize := fs.Int("batch", 20, "maximum number of feeds claimed per tick")
+	maxFailures := fs.Int("max-failures", 10, "consecutive failures before a feed is disabled (0 never disables)")
+	all := fs.Bool("all", false, "refresh every followed feed instead of the current user's feeds")
+	once := fs.Bool("once", false, "fetch every due feed once, print a summary and exit")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 && !*once {
 		return fmt.Errorf("time between requests is required")
 	}
+	if *workers < 1 || *batchSize < 1 {
+		return fmt.Errorf("workers and batch must be at least 1")
+	}
+
+	timeBetweenReqs := defaultRefreshInterval
+	if len(args) > 0 {
+		timeBetweenReqs, err = time.ParseDuration(args[0])
+		if err != nil {
+			return err
+		}
+	}
+
+	// Without --all only the feeds added by the current user are refreshed;
+	// a NULL user ID makes the claim query pick any feed that has followers.
+	var userID uuid.NullUUID
+	if !*all {
+		user, err := s.db.GetUser(ctx, s.cfg.CurrentUserName)
+		if err != nil {
+			return err
+		}
+		userID = uuid.NullUUID{UUID: user.ID, Valid: true}
+	}
+
+	opts := scrapeOptions{
+		workers:         *workers,
+		defaultInterval: timeBetweenReqs,
+		maxFailures:     *maxFailures,
+	}
+	// The lease keeps other agg processes away from claimed feeds until they
+	// are rescheduled, so it must outlast fetching the whole batch, which the
+	// workers work through in rounds.
+	rounds := (*batchSize + *workers - 1) / *workers
+	lease := max(timeBetweenReqs, time.Duration(rounds)*s.client.MaxFetchTime())
+	claim := func(dueBefore time.Time) ([]database.Feed, error) {
+		now := time.Now()
+		return s.db.ClaimFeedsToFetch(
+			ctx,
+			database.ClaimFeedsToFetchParams{
+				FetchedAt:  now,
+				LeaseUntil: now.Add(lease),
+				UserID:     userID,
+				DueBefore:  dueBefore,
+				BatchSize:  int32(*batchSize),
+			},
+		)
+	}
+
+	if *once {
+		return refreshOnce(ctx, s, claim, opts)
+	}
+
+	fmt.Printf("Collecting feeds every %s with %d workers\n", timeBetweenReqs.String(), *workers)
+
+	ticker := time.NewTicker(timeBetweenReqs)
+	defer ticker.Stop()
+	for {
+		feeds, err := claim(time.Now())
+		if err != nil && ctx.Err() == nil {
+			fmt.Println("failed to claim feeds", err)
+		}
+
+		scrapeFeeds(ctx, s, feeds, opts)
+
+		select {
+		case <-ctx.Done():
+			fmt.Println("Aggregator stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshOnce claims and fetches batches until no feed that was due when it
+// started is left. Fetched feeds are rescheduled after that cutoff, even when
+// they fail with a short retry delay, so each one is fetched exactly once.
+func refreshOnce(
+	ctx context.Context,
+	s *state,
+	claim func(dueBefore time.Time) ([]database.Feed, error),
+	opts scrapeOptions,
+) error {
+	dueBefore := time.Now()
+	var summary scrapeSummary
+	for ctx.Err() == nil {
+		feeds, err := claim(dueBefore)
+		if err != nil {
+			return err
+		}
+		if len(feeds) == 0 {
+			break
+		}
+		summary.add(scrapeFeeds(ctx, s, feeds, opts))
+	}
+
+	fmt.Printf(
+		"\nFetched: %d\nNew posts: %d\nUpdated posts: %d\nUnchanged: %d\nFailed: %d\n",
+		summary.fetched, summary.newPosts, summary.updatedPosts, summary.unchanged, summary.failed,
+	)
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if summary.failed > 0 {
+		return fmt.Errorf("%d feeds failed to refresh", summary.failed)
+	}
+	return nil
+}
+
+func handleFetch(ctx context.Context, s *state, cmd command) error {
+	if cmd.name != "fetch" {
+		return fmt.Errorf("invalid command")
+	}
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("feed url or name is required")
+	}
 
-	timeBetweenReqs, err := time.ParseDuration(cmd.args[0])
+	feed, err := findFeed(ctx, s, cmd.args[0])
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("Collecting feeds every %s\n", timeBetweenReqs.String())
+	// A manual fetch never disables the feed, and only a successful one
+	// re-enables it.
+	result, err := scrapeFeed(ctx, s, feed, scrapeOptions{
+		defaultInterval: feedInterval(feed, defaultRefreshInterval),
+	})
+	if err != nil {
+		return err
+	}
 
-	ticker := time.NewTicker(timeBetweenReqs)
-	for ; ; <-ticker.C {
-		scrapeFeeds(s, user)
+	if result.movedTo != "" {
+		fmt.Printf("%s moved permanently, its URL is now %s\n", feed.Name, result.movedTo)
+	}
+	if result.notModified {
+		fmt.Printf("%s: not modified since the last fetch\n", feed.Name)
+		return nil
+	}
+	fmt.Printf("Inserted %d posts, updated %d posts\n", result.newPosts, result.updatedPosts)
+	if len(result.itemErrors) > 0 {
+		fmt.Printf("%d items could not be saved:\n", len(result.itemErrors))
+		for _, itemErr := range result.itemErrors {
+			fmt.Printf("* %s: %v\n", itemErr.label(), itemErr.err)
+		}
+	}
+	if feed.DisabledAt.Valid {
+		fmt.Println("The feed was disabled and has been re-enabled")
 	}
+
+	return nil
 }
 
-func handleAddFeed(s *state, cmd command, user database.User) error {
+func handleAddFeed(ctx context.Context, s *state, cmd command, user database.User) error {
 	if cmd.name != "addfeed" {
 		return fmt.Errorf("invalid command")
 	}
-	if len(cmd.args) < 2 {
-		return fmt.Errorf("feed name and url is required")
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("feed url is required")
+	}
+
+	var feedName, feedUrl string
+	if len(cmd.args) == 1 {
+		feedUrl = cmd.args[0]
+	} else {
+		feedName = cmd.args[0]
+		feedUrl = cmd.args[1]
+	}
+
+	candidate, err := discoverFeed(ctx, s, feedUrl)
+	if err != nil {
+		return err
+	}
+	if candidate.URL != feedUrl {
+		fmt.Printf("Found feed %s\n", candidate.URL)
+	}
+
+	parsedFeed := candidate.Feed
+	if parsedFeed == nil {
+		result, err := s.client.Fetch(ctx, candidate.URL, "", "")
+		if err != nil {
+			return fmt.Errorf("%s is not a valid feed: %w", candidate.URL, err)
+		}
+		parsedFeed = result.Feed
 	}
 
-	feedName := cmd.args[0]
-	feedUrl := cmd.args[1]
+	if feedName == "" {
+		feedName = strings.TrimSpace(parsedFeed.Title)
+	}
+	if feedName == "" {
+		return fmt.Errorf("feed %s has no title, please give it a name", candidate.URL)
+	}
 
 	feed, err := s.db.CreateFeed(
-		context.Background(),
+		ctx,
 		database.CreateFeedParams{
-			ID:        uuid.New(),
-			Name:      feedName,
-			Url:       feedUrl,
-			UserID:    user.ID,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+			ID:          uuid.New(),
+			Name:        feedName,
+			Url:         candidate.URL,
+			UserID:      user.ID,
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+			Title:       nullString(parsedFeed.Title),
+			Link:        nullString(parsedFeed.Link),
+			Description: nullString(parsedFeed.Description),
+			Language:    nullString(parsedFeed.Language),
+			ImageUrl:    nullString(parsedFeed.ImageURL),
 		},
 	)
 	if err != nil {
@@ -236,7 +434,7 @@ func handleAddFeed(s *state, cmd command, user database.User) error {
 	)
 
 	_, err = s.db.CreateFeedFollow(
-		context.Background(),
+		ctx,
 		database.CreateFeedFollowParams{
 			ID:        uuid.New(),
 			UserID:    user.ID,
@@ -254,27 +452,34 @@ func handleAddFeed(s *state, cmd command, user database.User) error {
 	return nil
 }
 
-func handleFeeds(s *state, cmd command) error {
+func handleFeeds(ctx context.Context, s *state, cmd command) error {
 	if cmd.name != "feeds" {
 		return fmt.Errorf("invalid command")
 	}
 
-	feeds, err := s.db.GetFeeds(context.Background())
+	feeds, err := s.db.GetFeeds(ctx)
 	if err != nil {
 		return err
 	}
 
 	for _, feed := range feeds {
 		fmt.Printf(
-			"Name: %s\nURL: %s\nUser Name: %s",
+			"Name: %s\nURL: %s\nUser Name: %s\n",
 			feed.Name, feed.Url, feed.Username,
 		)
+		if feed.DisabledAt.Valid {
+			fmt.Printf("Disabled At: %s\n", feed.DisabledAt.Time.Local().String())
+		}
+		if feed.ErrorCount > 0 {
+			fmt.Printf("Failures: %d (last error: %s)\n", feed.ErrorCount, feed.LastError.String)
+		}
+		fmt.Println()
 	}
 
 	return nil
 }
 
-func handleFollow(s *state, cmd command, user database.User) error {
+func handleFollow(ctx context.Context, s *state, cmd command, user database.User) error {
 	if cmd.name != "follow" {
 		return fmt.Errorf("invalid command")
 	}
@@ -284,13 +489,16 @@ func handleFollow(s *state, cmd command, user database.User) error {
 
 	feedUrl := cmd.args[0]
 
-	feed, err := s.db.GetFeedByURL(context.Background(), feedUrl)
+	feed, err := s.db.GetFeedByURL(ctx, feedUrl)
+	if errors.Is(err, sql.ErrNoRows) {
+		feed, err = findDiscoveredFeed(ctx, s, feedUrl)
+	}
 	if err != nil {
 		return err
 	}
 
 	feed_follow, err := s.db.CreateFeedFollow(
-		context.Background(),
+		ctx,
 		database.CreateFeedFollowParams{
 			ID:        uuid.New(),
 			UserID:    user.ID,
@@ -311,13 +519,76 @@ func handleFollow(s *state, cmd command, user database.User) error {
 	return nil
 }
 
-func handleFollowing(s *state, cmd command, user database.User) error {
+// discoverFeed resolves a website URL to the one feed it publishes. A URL that
+// is already a feed resolves to itself.
+func discoverFeed(ctx context.Context, s *state, pageURL string) (feed.Candidate, error) {
+	candidates, err := s.client.Discover(ctx, pageURL)
+	if err != nil {
+		return feed.Candidate{}, err
+	}
+	switch len(candidates) {
+	case 0:
+		return feed.Candidate{}, fmt.Errorf("no feed found at %s", pageURL)
+	case 1:
+		return candidates[0], nil
+	default:
+		return feed.Candidate{}, fmt.Errorf("found %d feeds at %s, run again with one of:\n%s", len(candidates), pageURL, formatCandidates(candidates))
+	}
+}
+
+// findDiscoveredFeed looks up the feeds published by a website among the ones
+// that have already been added.
+func findDiscoveredFeed(ctx context.Context, s *state, pageURL string) (database.Feed, error) {
+	candidates, err := s.client.Discover(ctx, pageURL)
+	if err != nil {
+		return database.Feed{}, err
+	}
+	if len(candidates) == 0 {
+		return database.Feed{}, fmt.Errorf("no feed found at %s", pageURL)
+	}
+
+	var feeds []database.Feed
+	var added []feed.Candidate
+	for _, candidate := range candidates {
+		f, err := s.db.GetFeedByURL(ctx, candidate.URL)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return database.Feed{}, err
+		}
+		feeds = append(feeds, f)
+		added = append(added, candidate)
+	}
+
+	switch len(feeds) {
+	case 0:
+		return database.Feed{}, fmt.Errorf("feeds found at %s have not been added yet, add one with addfeed:\n%s", pageURL, formatCandidates(candidates))
+	case 1:
+		return feeds[0], nil
+	default:
+		return database.Feed{}, fmt.Errorf("found %d feeds at %s, run again with one of:\n%s", len(feeds), pageURL, formatCandidates(added))
+	}
+}
+
+func formatCandidates(candidates []feed.Candidate) string {
+	lines := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		lines[i] = "  " + candidate.URL
+		if candidate.Title != "" {
+			lines[i] += " (" + candidate.Title + ")"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func handleFollowing(ctx context.Context, s *state, cmd command, user database.User) error {
 	if cmd.name != "following" {
 		return fmt.Errorf("invalid command")
 	}
 
 	feed_follows, err := s.db.GetFeedFollowsForUser(
-		context.Background(),
+		ctx,
 		user.ID,
 	)
 	if err != nil {
@@ -325,16 +596,23 @@ func handleFollowing(s *state, cmd command, user database.User) error {
 	}
 
 	for _, feed_follow := range feed_follows {
+		status := ""
+		switch {
+		case feed_follow.FeedGoneAt.Valid:
+			status = " (gone)"
+		case feed_follow.FeedDisabledAt.Valid:
+			status = " (disabled)"
+		}
 		fmt.Printf(
-			"Feed Name: %s\n",
-			feed_follow.FeedName,
+			"Feed Name: %s%s (%d unread)\n",
+			feed_follow.FeedName, status, feed_follow.UnreadCount,
 		)
 	}
 
 	return nil
 }
 
-func handleUnfollow(s *state, cmd command, user database.User) error {
+func handleUnfollow(ctx context.Context, s *state, cmd command, user database.User) error {
 	if cmd.name != "unfollow" {
 		return fmt.Errorf("invalid command")
 	}
@@ -345,13 +623,13 @@ func handleUnfollow(s *state, cmd command, user database.User) error {
 
 	feedUrl := cmd.args[0]
 
-	feed, err := s.db.GetFeedByURL(context.Background(), feedUrl)
+	feed, err := s.db.GetFeedByURL(ctx, feedUrl)
 	if err != nil {
 		return err
 	}
 
 	err = s.db.DeleteFeedFollowByUserIDAndFeedID(
-		context.Background(),
+		ctx,
 		database.DeleteFeedFollowByUserIDAndFeedIDParams{
 			UserID: user.ID,
 			FeedID: feed.ID,
@@ -366,162 +644,1047 @@ func handleUnfollow(s *state, cmd command, user database.User) error {
 	return nil
 }
 
-func handleBrowse(s *state, cmd command, user database.User) error {
-	if cmd.name != "browse" {
+func handleImport(ctx context.Context, s *state, cmd command, user database.User) error {
+	if cmd.name != "import" {
 		return fmt.Errorf("invalid command")
 	}
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("opml file is required")
+	}
 
-	limit := int32(2)
-
-	if len(cmd.args) == 1 {
-		limitArg, err := strconv.Atoi(cmd.args[0])
-		if err != nil {
-			return err
-		}
-		limit = int32(limitArg)
+	file, err := os.Open(cmd.args[0])
+	if err != nil {
+		return err
 	}
+	defer file.Close()
 
-	posts, err := s.db.GetPostsForUser(
-		context.Background(),
-		database.GetPostsForUserParams{
-			UserID: user.ID,
-			Limit:  limit,
-		},
-	)
+	doc, err := opml.Parse(file)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", cmd.args[0], err)
+	}
 
+	feed_follows, err := s.db.GetFeedFollowsForUser(ctx, user.ID)
 	if err != nil {
 		return err
 	}
+	following := make(map[uuid.UUID]bool, len(feed_follows))
+	for _, feed_follow := range feed_follows {
+		following[feed_follow.FeedID] = true
+	}
 
-	fmt.Printf("Found %d posts\n\n", len(posts))
-
-	for _, post := range posts {
-		fmt.Printf(
-			"Title: %s\nURL: %s\nDescription: %s\nPublished At: %s\n\n",
-			post.Title, post.Url, post.Description, post.PublishedAt.Local().String(),
-		)
+	tx, err := s.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
+	qtx := s.db.WithTx(tx)
+
+	var added, followed, skipped, invalid []string
+	for _, subscription := range doc.Subscriptions() {
+		name := subscription.Title
+		if name == "" {
+			name = subscription.URL
+		}
 
-	return nil
-}
+		err := validateFeedURL(subscription.URL)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
 
-func middlewareLoggedIn(
-	handler func(s *state, cmd command, user database.User) error,
-) func(*state, command) error {
-	return func(s *state, c command) error {
-		user, err := s.db.GetUser(context.Background(), s.cfg.CurrentUserName)
+		feed, err := qtx.GetFeedByURL(ctx, subscription.URL)
+		isNew := errors.Is(err, sql.ErrNoRows)
+		if isNew {
+			feed, err = qtx.CreateFeed(
+				ctx,
+				database.CreateFeedParams{
+					ID:        uuid.New(),
+					Name:      name,
+					Url:       subscription.URL,
+					UserID:    user.ID,
+					CreatedAt: time.Now(),
+					UpdatedAt: time.Now(),
+					Link:      nullString(subscription.SiteURL),
+				},
+			)
+		}
+		if err != nil {
+			return err
+		}
+
+		if following[feed.ID] {
+			skipped = append(skipped, fmt.Sprintf("%s (%s)", name, subscription.URL))
+			continue
+		}
+		_, err = qtx.CreateFeedFollow(
+			ctx,
+			database.CreateFeedFollowParams{
+				ID:        uuid.New(),
+				UserID:    user.ID,
+				FeedID:    feed.ID,
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				Folder:    nullString(subscription.Folder),
+			},
+		)
 		if err != nil {
 			return err
 		}
+		following[feed.ID] = true
 
-		return handler(s, c, user)
+		if isNew {
+			added = append(added, fmt.Sprintf("%s (%s)", name, subscription.URL))
+		} else {
+			followed = append(followed, fmt.Sprintf("%s (%s)", name, subscription.URL))
+		}
 	}
-}
 
-func (c *commands) run(s *state, cmd command) error {
-	f, exists := c.cmds[cmd.name]
-	if !exists {
-		return fmt.Errorf("invalid command")
+	err = tx.Commit()
+	if err != nil {
+		return err
 	}
-	return f(s, cmd)
-}
 
-func (c *commands) register(name string, f func(*state, command) error) {
-	c.cmds[name] = f
+	printImportSection("Added", added)
+	printImportSection("Followed existing", followed)
+	printImportSection("Skipped, already following", skipped)
+	printImportSection("Invalid", invalid)
+	fmt.Printf(
+		"Imported %d feeds: %d added, %d already added, %d skipped, %d invalid\n",
+		len(added)+len(followed), len(added), len(followed), len(skipped), len(invalid),
+	)
+
+	return nil
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
-	if err != nil {
-		return nil, err
+func handleExport(ctx context.Context, s *state, cmd command, user database.User) error {
+	if cmd.name != "export" {
+		return fmt.Errorf("invalid command")
 	}
-	req.Header.Set("User-Agent", "gator")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "opml", "export format (only opml is supported)")
+	output := fs.String("output", "", "file to write to instead of stdout")
+	_, err := parseFlags(fs, cmd.args)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if *format != "opml" {
+		return fmt.Errorf("unsupported export format %q", *format)
 	}
-	defer resp.Body.Close()
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	feed_follows, err := s.db.GetFeedFollowsForUser(ctx, user.ID)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	subscriptions := make([]opml.Subscription, len(feed_follows))
+	for i, feed_follow := range feed_follows {
+		subscriptions[i] = opml.Subscription{
+			Title:   feed_follow.FeedName,
+			URL:     feed_follow.FeedUrl,
+			SiteURL: feed_follow.FeedLink.String,
+			Folder:  feed_follow.Folder.String,
+		}
+	}
+	slices.SortFunc(subscriptions, func(a, b opml.Subscription) int {
+		return cmp.Or(
+			strings.Compare(a.Folder, b.Folder),
+			strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title)),
+		)
+	})
+
+	doc := opml.New(fmt.Sprintf("%s's subscriptions in gator", user.Name), subscriptions)
+	doc.Head.DateCreated = time.Now().Format(time.RFC1123Z)
+
+	if *output == "" {
+		return doc.Write(os.Stdout)
 	}
 
-	var rssFeed RSSFeed
-	err = xml.Unmarshal(bodyBytes, &rssFeed)
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	err = doc.Write(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	err = file.Close()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	rssFeed.Channel.Title = html.UnescapeString(rssFeed.Channel.Title)
-	rssFeed.Channel.Description = html.UnescapeString(rssFeed.Channel.Description)
-	for idx, item := range rssFeed.Channel.Item {
-		rssFeed.Channel.Item[idx].Title = html.UnescapeString(item.Title)
-		rssFeed.Channel.Item[idx].Description = html.UnescapeString(item.Description)
+	fmt.Printf("Exported %d feeds to %s\n", len(subscriptions), *output)
+
+	return nil
+}
+
+func printImportSection(title string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Printf("%s:\n", title)
+	for _, line := range lines {
+		fmt.Printf("  %s\n", line)
 	}
+}
 
-	return &rssFeed, nil
+func validateFeedURL(rawURL string) error {
+	if rawURL == "" {
+		return fmt.Errorf("missing feed url")
+	}
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" || parsedURL.Host == "" {
+		return fmt.Errorf("%q is not an http(s) url", rawURL)
+	}
+	return nil
 }
 
-func scrapeFeeds(s *state, user database.User) error {
-	feed, err := s.db.GetNextFeedToFetch(context.Background(), user.ID)
+func handleBrowse(ctx context.Context, s *state, cmd command, user database.User) error {
+	if cmd.name != "browse" {
+		return fmt.Errorf("invalid command")
+	}
+
+	fs := flag.NewFlagSet("browse", flag.ContinueOnError)
+	all := fs.Bool("all", false, "include posts that have already been read")
+	unread := fs.Bool("unread", true, "only show unread posts")
+	args, err := parseFlags(fs, cmd.args)
 	if err != nil {
 		return err
 	}
+	unreadOnly := *unread && !*all
 
-	err = s.db.MarkFeedFetched(
-		context.Background(),
-		database.MarkFeedFetchedParams{
-			ID:            feed.ID,
-			UserID:        user.ID,
-			LastFetchedAt: sql.NullTime{Time: time.Now(), Valid: true},
-			UpdatedAt:     time.Now(),
+	limit := int32(2)
+
+	if len(args) == 1 {
+		limitArg, err := strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+		limit = int32(limitArg)
+	}
+
+	posts, err := s.db.GetPostsForUser(
+		ctx,
+		database.GetPostsForUserParams{
+			UserID:     user.ID,
+			UnreadOnly: unreadOnly,
+			PostLimit:  limit,
 		},
 	)
+
 	if err != nil {
 		return err
 	}
 
-	rssFeed, err := fetchFeed(context.Background(), feed.Url)
+	feed_follows, err := s.db.GetFeedFollowsForUser(ctx, user.ID)
 	if err != nil {
 		return err
 	}
+	for _, feed_follow := range feed_follows {
+		if feed_follow.FeedGoneAt.Valid {
+			fmt.Printf(
+				"Notice: %s is gone since %s and will not get new posts, consider unfollowing it\n\n",
+				feed_follow.FeedName, feed_follow.FeedGoneAt.Time.Local().String(),
+			)
+		}
+	}
 
-	fmt.Printf("Title: %s\n", rssFeed.Channel.Title)
-	fmt.Printf("Link: %s\n", rssFeed.Channel.Link)
-	fmt.Printf("Description: %s\n\n", rssFeed.Channel.Description)
+	if unreadOnly {
+		fmt.Printf("Found %d unread posts\n\n", len(posts))
+	} else {
+		fmt.Printf("Found %d posts\n\n", len(posts))
+	}
 
-	for _, item := range rssFeed.Channel.Item {
-		parsedPubDate, err := anytime.Parse(item.PubDate)
-		if err != nil {
-			fmt.Println("date parsing did not work", err)
-			continue
+	for _, post := range posts {
+		fmt.Printf(
+			"ID: %s\nTitle: %s\nURL: %s\nDescription: %s\nPublished At: %s\n",
+			post.ID, post.Title, post.Url, post.Description, post.PublishedAt.Local().String(),
+		)
+		if post.RevisedAt.Valid {
+			fmt.Printf("Revised At: %s\n", post.RevisedAt.Time.Local().String())
+		}
+		if !unreadOnly && post.Read {
+			fmt.Println("Read: yes")
 		}
+		if post.Starred {
+			fmt.Println("Starred: yes")
+		}
+		fmt.Println()
+	}
 
-		err = s.db.CreatePost(
-			context.Background(),
-			database.CreatePostParams{
-				ID:          uuid.New(),
-				Title:       item.Title,
-				Url:         item.Link,
-				Description: item.Description,
-				PublishedAt: parsedPubDate,
-				FeedID:      feed.ID,
-				CreatedAt:   time.Now(),
-				UpdatedAt:   time.Now(),
-			},
-		)
+	return nil
+}
+
+func handleRead(ctx context.Context, s *state, cmd command, user database.User) error {
+	if cmd.name != "read" {
+		return fmt.Errorf("invalid command")
+	}
+
+	fs := flag.NewFlagSet("read", flag.ContinueOnError)
+	all := fs.Bool("all", false, "mark every post of the feeds you follow as read")
+	feedArg := fs.String("feed", "", "with --all, only mark posts of this feed (url or name)")
+	olderThan := fs.String("older-than", "", "with --all, only mark posts published longer ago than this, e.g. 7d or 12h")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+
+	if !*all {
+		if *feedArg != "" || *olderThan != "" {
+			return fmt.Errorf("--feed and --older-than can only be used with --all")
+		}
+		if len(args) < 1 {
+			return fmt.Errorf("post id is required")
+		}
+		err = setPostRead(ctx, s, user, args[0], true)
 		if err != nil {
-			if pgerr, ok := err.(*pq.Error); ok {
-				if pgerr.Code != UniqueViolationError {
-					fmt.Println("failed to add a post", err)
-				}
-			}
-			continue
+			return err
 		}
+		fmt.Println("Marked post as read")
+		return nil
 	}
 
+	params := database.MarkPostsReadParams{
+		UserID: user.ID,
+		ReadAt: time.Now(),
+	}
+	if *feedArg != "" {
+		feed, err := findFeed(ctx, s, *feedArg)
+		if err != nil {
+			return err
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	if *olderThan != "" {
+		age, err := parseAge(*olderThan)
+		if err != nil {
+			return err
+		}
+		params.PublishedBefore = sql.NullTime{Time: time.Now().Add(-age), Valid: true}
+	}
+
+	count, err := s.db.MarkPostsRead(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Marked %d posts as read\n", count)
+
+	return nil
+}
+
+func handleUnread(ctx context.Context, s *state, cmd command, user database.User) error {
+	if cmd.name != "unread" {
+		return fmt.Errorf("invalid command")
+	}
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("post id is required")
+	}
+
+	err := setPostRead(ctx, s, user, cmd.args[0], false)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Marked post as unread")
+
+	return nil
+}
+
+func setPostRead(ctx context.Context, s *state, user database.User, postArg string, read bool) error {
+	postID, err := uuid.Parse(postArg)
+	if err != nil {
+		return fmt.Errorf("invalid post id %q", postArg)
+	}
+
+	count, err := s.db.SetPostRead(
+		ctx,
+		database.SetPostReadParams{
+			UserID:    user.ID,
+			PostID:    postID,
+			Read:      read,
+			ReadAt:    sql.NullTime{Time: time.Now(), Valid: read},
+			UpdatedAt: time.Now(),
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("no post %s in the feeds you follow", postID)
+	}
+	return nil
+}
+
+func handleStar(ctx context.Context, s *state, cmd command, user database.User) error {
+	if cmd.name != "star" {
+		return fmt.Errorf("invalid command")
+	}
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("post id is required")
+	}
+
+	postID, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("invalid post id %q", cmd.args[0])
+	}
+
+	count, err := s.db.StarPost(
+		ctx,
+		database.StarPostParams{
+			UserID:    user.ID,
+			PostID:    postID,
+			StarredAt: time.Now(),
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("no post %s in the feeds you follow", postID)
+	}
+
+	fmt.Println("Starred post")
+
+	return nil
+}
+
+func handleUnstar(ctx context.Context, s *state, cmd command, user database.User) error {
+	if cmd.name != "unstar" {
+		return fmt.Errorf("invalid command")
+	}
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("post id is required")
+	}
+
+	postID, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("invalid post id %q", cmd.args[0])
+	}
+
+	count, err := s.db.UnstarPost(
+		ctx,
+		database.UnstarPostParams{
+			UserID:    user.ID,
+			PostID:    postID,
+			UpdatedAt: time.Now(),
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("post %s is not starred", postID)
+	}
+
+	fmt.Println("Unstarred post")
+
 	return nil
 }
+
+func handleStarred(ctx context.Context, s *state, cmd command, user database.User) error {
+	if cmd.name != "starred" {
+		return fmt.Errorf("invalid command")
+	}
+
+	limit := int32(10)
+
+	if len(cmd.args) == 1 {
+		limitArg, err := strconv.Atoi(cmd.args[0])
+		if err != nil {
+			return err
+		}
+		limit = int32(limitArg)
+	}
+
+	posts, err := s.db.GetStarredPostsForUser(
+		ctx,
+		database.GetStarredPostsForUserParams{
+			UserID:    user.ID,
+			PostLimit: limit,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Found %d starred posts\n\n", len(posts))
+
+	for _, post := range posts {
+		fmt.Printf(
+			"ID: %s\nTitle: %s\nURL: %s\nDescription: %s\nPublished At: %s\nStarred At: %s\n\n",
+			post.ID, post.Title, post.Url, post.Description, post.PublishedAt.Local().String(), post.StarredAt.Time.Local().String(),
+		)
+	}
+
+	return nil
+}
+
+func handleSearch(ctx context.Context, s *state, cmd command, user database.User) error {
+	if cmd.name != "search" {
+		return fmt.Errorf("invalid command")
+	}
+
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	feedArg := fs.String("feed", "", "only search posts of this feed (url or name)")
+	since := fs.String("since", "", "only search posts published on or after this date (2006-01-02) or age (7d)")
+	until := fs.String("until", "", "only search posts published on or before this date (2006-01-02) or age (7d)")
+	limit := fs.Int("limit", 10, "maximum number of posts to show")
+	args, err := parseFlags(fs, cmd.args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 {
+		return fmt.Errorf("search query is required")
+	}
+
+	params := database.SearchPostsForUserParams{
+		Query:     strings.Join(args, " "),
+		UserID:    user.ID,
+		PostLimit: int32(*limit),
+	}
+	if *feedArg != "" {
+		feed, err := findFeed(ctx, s, *feedArg)
+		if err != nil {
+			return err
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	if *since != "" {
+		from, _, err := parseDateOrAge(*since)
+		if err != nil {
+			return err
+		}
+		params.PublishedAfter = sql.NullTime{Time: from, Valid: true}
+	}
+	if *until != "" {
+		_, to, err := parseDateOrAge(*until)
+		if err != nil {
+			return err
+		}
+		params.PublishedBefore = sql.NullTime{Time: to, Valid: true}
+	}
+
+	posts, err := s.db.SearchPostsForUser(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Found %d posts matching %q\n\n", len(posts), params.Query)
+
+	for _, post := range posts {
+		fmt.Printf(
+			"ID: %s\nTitle: %s\nFeed: %s\nURL: %s\nPublished At: %s\n",
+			post.ID, post.Title, post.FeedName, post.Url, post.PublishedAt.Local().String(),
+		)
+		if post.Snippet != "" {
+			fmt.Printf("Snippet: %s\n", post.Snippet)
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
+
+func middlewareLoggedIn(
+	handler func(ctx context.Context, s *state, cmd command, user database.User) error,
+) func(context.Context, *state, command) error {
+	return func(ctx context.Context, s *state, c command) error {
+		user, err := s.db.GetUser(ctx, s.cfg.CurrentUserName)
+		if err != nil {
+			return err
+		}
+
+		return handler(ctx, s, c, user)
+	}
+}
+
+// maxBackoff bounds the retry delay of a failing feed.
+const maxBackoff = 24 * time.Hour
+
+// recordFeedFailure stores the fetch error and pushes the feed's next fetch
+// back exponentially, disabling it after opts.maxFailures consecutive errors.
+func recordFeedFailure(ctx context.Context, s *state, feed database.Feed, opts scrapeOptions, fetchErr error) error {
+	failures := int(feed.ErrorCount) + 1
+	backoff := feedInterval(feed, opts.defaultInterval)
+	for i := 1; i < failures && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, maxBackoff)
+
+	var disabledAt sql.NullTime
+	if opts.maxFailures > 0 && failures >= opts.maxFailures {
+		disabledAt = sql.NullTime{Time: time.Now(), Valid: true}
+		fmt.Printf("%s: disabled after %d consecutive failures\n", feed.Name, failures)
+	}
+
+	return s.db.RecordFeedFailure(
+		ctx,
+		database.RecordFeedFailureParams{
+			ID:          feed.ID,
+			LastError:   sql.NullString{String: fetchErr.Error(), Valid: true},
+			NextFetchAt: sql.NullTime{Time: time.Now().Add(backoff), Valid: true},
+			DisabledAt:  disabledAt,
+			UpdatedAt:   time.Now(),
+		},
+	)
+}
+
+// moveFeed points the feed at the URL it permanently redirects to. When
+// another feed already uses that URL, the two are merged: follows and posts
+// move over to the existing feed and the old one is deleted.
+func moveFeed(ctx context.Context, s *state, dbFeed database.Feed, newURL string) (database.Feed, error) {
+	tx, err := s.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return dbFeed, err
+	}
+	defer tx.Rollback()
+	qtx := s.db.WithTx(tx)
+
+	existing, err := qtx.GetFeedByURL(ctx, newURL)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		err = qtx.UpdateFeedURL(
+			ctx,
+			database.UpdateFeedURLParams{
+				ID:        dbFeed.ID,
+				Url:       newURL,
+				UpdatedAt: time.Now(),
+			},
+		)
+		if err != nil {
+			return dbFeed, err
+		}
+		dbFeed.Url = newURL
+	case err != nil:
+		return dbFeed, err
+	default:
+		err = qtx.MoveFeedFollows(
+			ctx,
+			database.MoveFeedFollowsParams{
+				ToFeedID:   existing.ID,
+				FromFeedID: dbFeed.ID,
+			},
+		)
+		if err != nil {
+			return dbFeed, err
+		}
+		err = qtx.MovePosts(
+			ctx,
+			database.MovePostsParams{
+				ToFeedID:   existing.ID,
+				FromFeedID: dbFeed.ID,
+			},
+		)
+		if err != nil {
+			return dbFeed, err
+		}
+		// Posts both feeds share are deleted with the old feed, so their
+		// read and starred state is carried over to the existing copies.
+		err = qtx.MovePostStates(
+			ctx,
+			database.MovePostStatesParams{
+				ToFeedID:   existing.ID,
+				FromFeedID: dbFeed.ID,
+			},
+		)
+		if err != nil {
+			return dbFeed, err
+		}
+		err = qtx.DeleteFeed(ctx, dbFeed.ID)
+		if err != nil {
+			return dbFeed, err
+		}
+		dbFeed = existing
+	}
+
+	return dbFeed, tx.Commit()
+}
+
+func feedInterval(feed database.Feed, defaultInterval time.Duration) time.Duration {
+	if feed.RefreshIntervalSeconds.Valid && feed.RefreshIntervalSeconds.Int32 > 0 {
+		return time.Duration(feed.RefreshIntervalSeconds.Int32) * time.Second
+	}
+	return defaultInterval
+}
+
+func scheduleNextFetch(ctx context.Context, s *state, feedID uuid.UUID, interval time.Duration, next time.Time) error {
+	return s.db.ScheduleNextFetch(
+		ctx,
+		database.ScheduleNextFetchParams{
+			ID:                     feedID,
+			RefreshIntervalSeconds: sql.NullInt32{Int32: int32(interval.Seconds()), Valid: true},
+			NextFetchAt:            sql.NullTime{Time: next, Valid: true},
+			UpdatedAt:              time.Now(),
+		},
+	)
+}
+
+// nullString stores blank strings as NULL.
+func nullString(value string) sql.NullString {
+	value = strings.TrimSpace(value)
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// findFeed looks a feed up by its url or, failing that, its name, which unlike
+// the url is not guaranteed to be unique.
+func findFeed(ctx context.Context, s *state, urlOrName string) (database.Feed, error) {
+	feeds, err := s.db.GetFeedsByURLOrName(ctx, urlOrName)
+	if err != nil {
+		return database.Feed{}, err
+	}
+	if len(feeds) == 0 {
+		return database.Feed{}, fmt.Errorf("no feed with url or name %q", urlOrName)
+	}
+	if len(feeds) > 1 {
+		return database.Feed{}, fmt.Errorf("%d feeds are named %q, use the feed url instead", len(feeds), urlOrName)
+	}
+	return feeds[0], nil
+}
+
+// parseAge is time.ParseDuration with an additional "d" unit for whole days.
+func parseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q", value)
+	}
+	return age, nil
+}
+
+// parseDateOrAge accepts either a calendar date, returning the start and end
+// of that day, or an age such as 7d, returning the moment that long ago as
+// both.
+func parseDateOrAge(value string) (time.Time, time.Time, error) {
+	day, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err == nil {
+		return day, day.AddDate(0, 0, 1), nil
+	}
+	age, err := parseAge(value)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date or age %q", value)
+	}
+	moment := time.Now().Add(-age)
+	return moment, moment, nil
+}
+
+// parseFlags parses flags that may appear before, between or after the
+// positional arguments and returns the positional arguments in order.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func (c *commands) run(ctx context.Context, s *state, cmd command) error {
+	f, exists := c.cmds[cmd.name]
+	if !exists {
+		return fmt.Errorf("invalid command")
+	}
+	return f(ctx, s, cmd)
+}
+
+func (c *commands) register(name string, f func(context.Context, *state, command) error) {
+	c.cmds[name] = f
+}
+
+// defaultRefreshInterval is used by agg --once when no interval is given.
+const defaultRefreshInterval = time.Hour
+
+type scrapeOptions struct {
+	workers         int
+	defaultInterval time.Duration
+	maxFailures     int
+}
+
+type scrapeResult struct {
+	movedTo      string
+	notModified  bool
+	newPosts     int
+	updatedPosts int
+	itemErrors   []itemError
+}
+
+type itemError struct {
+	item feed.Item
+	err  error
+}
+
+func (r scrapeResult) print(feedName string) {
+	if r.movedTo != "" {
+		fmt.Printf("%s: moved permanently to %s\n", feedName, r.movedTo)
+	}
+	if r.notModified {
+		fmt.Printf("%s: not modified\n", feedName)
+		return
+	}
+	fmt.Printf("%s: saved %d new posts, updated %d posts\n", feedName, r.newPosts, r.updatedPosts)
+	for _, itemErr := range r.itemErrors {
+		fmt.Printf("%s: skipped %s: %v\n", feedName, itemErr.label(), itemErr.err)
+	}
+}
+
+func (e itemError) label() string {
+	if e.item.Title != "" {
+		return fmt.Sprintf("%q", e.item.Title)
+	}
+	return e.item.GUID()
+}
+
+type scrapeSummary struct {
+	fetched      int
+	unchanged    int
+	failed       int
+	newPosts     int
+	updatedPosts int
+}
+
+func (sum *scrapeSummary) add(other scrapeSummary) {
+	sum.fetched += other.fetched
+	sum.unchanged += other.unchanged
+	sum.failed += other.failed
+	sum.newPosts += other.newPosts
+	sum.updatedPosts += other.updatedPosts
+}
+
+func scrapeFeeds(ctx context.Context, s *state, feeds []database.Feed, opts scrapeOptions) scrapeSummary {
+	var summary scrapeSummary
+	var mu sync.Mutex
+	jobs := make(chan database.Feed)
+	var wg sync.WaitGroup
+	for range opts.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for feed := range jobs {
+				result, err := scrapeFeed(ctx, s, feed, opts)
+				if errors.Is(err, context.Canceled) {
+					continue
+				}
+				if err != nil {
+					fmt.Printf("failed to fetch %s: %v\n", feed.Url, err)
+				} else {
+					result.print(feed.Name)
+				}
+
+				mu.Lock()
+				switch {
+				case err != nil:
+					summary.failed++
+				case result.newPosts == 0 && result.updatedPosts == 0:
+					summary.fetched++
+					summary.unchanged++
+				default:
+					summary.fetched++
+				}
+				summary.newPosts += result.newPosts
+				summary.updatedPosts += result.updatedPosts
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, feed := range feeds {
+		jobs <- feed
+	}
+	close(jobs)
+	wg.Wait()
+	return summary
+}
+
+func scrapeFeed(ctx context.Context, s *state, dbFeed database.Feed, opts scrapeOptions) (scrapeResult, error) {
+	result, err := s.client.Fetch(ctx, dbFeed.Url, dbFeed.Etag.String, dbFeed.LastModified.String)
+	if err != nil && ctx.Err() != nil {
+		// Shutting down is not the feed's fault: hand the claim back so the
+		// feed is due again as soon as an aggregator is running.
+		releaseErr := scheduleNextFetch(context.WithoutCancel(ctx), s, dbFeed.ID, feedInterval(dbFeed, opts.defaultInterval), time.Now())
+		return scrapeResult{}, errors.Join(ctx.Err(), releaseErr)
+	}
+
+	// Once the feed is downloaded its posts are stored even if a shutdown
+	// was requested in the meantime.
+	ctx = context.WithoutCancel(ctx)
+
+	// A host asking us to slow down is not a broken feed, so it does not
+	// count towards disabling it.
+	var retryErr *feed.RetryAfterError
+	if errors.As(err, &retryErr) && retryErr.Delay > 0 {
+		next := time.Now().Add(min(retryErr.Delay, maxBackoff))
+		return scrapeResult{}, errors.Join(err, scheduleNextFetch(ctx, s, dbFeed.ID, feedInterval(dbFeed, opts.defaultInterval), next))
+	}
+	if errors.Is(err, feed.ErrGone) {
+		return scrapeResult{}, errors.Join(err, s.db.MarkFeedGone(
+			ctx,
+			database.MarkFeedGoneParams{
+				ID:        dbFeed.ID,
+				LastError: sql.NullString{String: err.Error(), Valid: true},
+				GoneAt:    sql.NullTime{Time: time.Now(), Valid: true},
+			},
+		))
+	}
+	if err != nil {
+		return scrapeResult{}, errors.Join(err, recordFeedFailure(ctx, s, dbFeed, opts, err))
+	}
+
+	var movedTo string
+	if result.PermanentURL != "" && result.PermanentURL != dbFeed.Url {
+		dbFeed, err = moveFeed(ctx, s, dbFeed, result.PermanentURL)
+		if err != nil {
+			return scrapeResult{}, err
+		}
+		movedTo = result.PermanentURL
+	}
+
+	err = s.db.RecordFeedSuccess(
+		ctx,
+		database.RecordFeedSuccessParams{
+			ID:            dbFeed.ID,
+			LastSuccessAt: sql.NullTime{Time: time.Now(), Valid: true},
+			UpdatedAt:     time.Now(),
+		},
+	)
+	if err != nil {
+		return scrapeResult{}, err
+	}
+
+	if result.NotModified {
+		interval := feedInterval(dbFeed, opts.defaultInterval)
+		err = scheduleNextFetch(ctx, s, dbFeed.ID, interval, time.Now().Add(interval))
+		if err != nil {
+			return scrapeResult{}, err
+		}
+		return scrapeResult{notModified: true, movedTo: movedTo}, nil
+	}
+
+	parsedFeed := result.Feed
+	interval := parsedFeed.Schedule.Interval(opts.defaultInterval)
+	err = scheduleNextFetch(ctx, s, dbFeed.ID, interval, parsedFeed.Schedule.NextFetch(time.Now(), interval))
+	if err != nil {
+		return scrapeResult{}, err
+	}
+
+	err = s.db.UpdateFeedMetadata(
+		ctx,
+		database.UpdateFeedMetadataParams{
+			ID:          dbFeed.ID,
+			Title:       nullString(parsedFeed.Title),
+			Link:        nullString(parsedFeed.Link),
+			Description: nullString(parsedFeed.Description),
+			Language:    nullString(parsedFeed.Language),
+			ImageUrl:    nullString(parsedFeed.ImageURL),
+			UpdatedAt:   time.Now(),
+		},
+	)
+	if err != nil {
+		return scrapeResult{}, err
+	}
+
+	scraped := scrapeResult{movedTo: movedTo}
+	// Invalid dates will not parse on a retry either, but failed saves
+	// might, so they keep the feed from answering the next fetch with 304.
+	saveFailed := false
+	for _, item := range parsedFeed.Items {
+		parsedPubDate, err := anytime.Parse(item.PubDate)
+		if err != nil {
+			scraped.itemErrors = append(scraped.itemErrors, itemError{
+				item: item,
+				err:  fmt.Errorf("invalid publication date %q: %w", item.PubDate, err),
+			})
+			continue
+		}
+
+		// Posts saved before guids were introduced used their link as the
+		// guid. Adopting the item's real guid keeps them from being
+		// inserted a second time.
+		if item.GUID() != item.Link {
+			err = s.db.UpdateLegacyPostGUID(
+				ctx,
+				database.UpdateLegacyPostGUIDParams{
+					Guid:   item.GUID(),
+					FeedID: dbFeed.ID,
+					Url:    item.Link,
+				},
+			)
+			if err != nil {
+				scraped.itemErrors = append(scraped.itemErrors, itemError{
+					item: item,
+					err:  fmt.Errorf("failed to update legacy post: %w", err),
+				})
+				saveFailed = true
+				continue
+			}
+		}
+
+		inserted, err := s.db.CreatePost(
+			ctx,
+			database.CreatePostParams{
+				ID:          uuid.New(),
+				Title:       item.Title,
+				Url:         item.Link,
+				Description: item.Description,
+				PublishedAt: parsedPubDate,
+				FeedID:      dbFeed.ID,
+				CreatedAt:   time.Now(),
+				UpdatedAt:   time.Now(),
+				Guid:        item.GUID(),
+				ContentHash: item.ContentHash(),
+			},
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			scraped.itemErrors = append(scraped.itemErrors, itemError{
+				item: item,
+				err:  fmt.Errorf("failed to save post: %w", err),
+			})
+			saveFailed = true
+			continue
+		}
+		if inserted {
+			scraped.newPosts++
+		} else {
+			scraped.updatedPosts++
+		}
+	}
+
+	// The validators are only stored once the posts are saved, so a crash in
+	// between or a failed save cannot hide posts behind 304 responses.
+	if saveFailed {
+		return scraped, nil
+	}
+	err = s.db.SetFeedCacheValidators(
+		ctx,
+		database.SetFeedCacheValidatorsParams{
+			ID:           dbFeed.ID,
+			Etag:         sql.NullString{String: result.ETag, Valid: result.ETag != ""},
+			LastModified: sql.NullString{String: result.LastModified, Valid: result.LastModified != ""},
+			UpdatedAt:    time.Now(),
+		},
+	)
+	if err != nil {
+		return scrapeResult{}, err
+	}
+
+	return scraped, nil
+}
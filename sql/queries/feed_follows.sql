@@ -1,7 +1,14 @@
 -- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
-  INSERT INTO feed_follows (id, user_id, feed_id, created_at, updated_at)
-  VALUES ($1, $2, $3, $4, $5)
+  INSERT INTO feed_follows (
+      id,
+      user_id,
+      feed_id,
+      created_at,
+      updated_at,
+      folder
+    )
+  VALUES ($1, $2, $3, $4, $5, $6)
   RETURNING *
 )
 SELECT inserted_feed_follow.*,
@@ -14,7 +21,19 @@ FROM inserted_feed_follow
 -- name: GetFeedFollowsForUser :many
 SELECT ff.*,
   f.name AS feed_name,
-  u.name AS user_name
+  f.url AS feed_url,
+  f.link AS feed_link,
+  u.name AS user_name,
+  f.disabled_at AS feed_disabled_at,
+  f.gone_at AS feed_gone_at,
+  (
+    SELECT COUNT(*)
+    FROM posts p
+      LEFT JOIN post_states ps ON ps.post_id = p.id
+      AND ps.user_id = ff.user_id
+    WHERE p.feed_id = ff.feed_id
+      AND NOT COALESCE(ps.read, FALSE)
+  ) AS unread_count
 FROM feed_follows ff
   JOIN users u ON u.id = ff.user_id
   JOIN feeds f ON f.id = ff.feed_id
@@ -23,4 +42,14 @@ WHERE ff.user_id = $1;
 -- name: DeleteFeedFollowByUserIDAndFeedID :exec
 DELETE FROM feed_follows
 WHERE user_id = $1
-  AND feed_id = $2;
\ No newline at end of file
+  AND feed_id = $2;
+
+-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id)
+  AND user_id NOT IN (
+    SELECT user_id
+    FROM feed_follows
+    WHERE feed_id = sqlc.arg(to_feed_id)
+  );
\ No newline at end of file
@@ -1,6 +1,18 @@
 -- name: CreateFeed :one
-INSERT INTO feeds (id, name, url, user_id, created_at, updated_at)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO feeds (
+    id,
+    name,
+    url,
+    user_id,
+    created_at,
+    updated_at,
+    title,
+    link,
+    description,
+    language,
+    image_url
+  )
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 RETURNING *;
 
 -- name: GetFeedByURL :one
@@ -8,23 +20,110 @@ SELECT *
 FROM feeds
 WHERE url = $1;
 
+-- name: GetFeedsByURLOrName :many
+SELECT *
+FROM feeds
+WHERE url = sqlc.arg(url_or_name)
+  OR name = sqlc.arg(url_or_name);
+
 -- name: GetFeeds :many
 SELECT f.name,
   f.url,
+  f.error_count,
+  f.last_error,
+  f.disabled_at,
   u.name AS username
 FROM feeds f
   JOIN users u ON u.id = f.user_id;
 
--- name: MarkFeedFetched :exec
+-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET last_fetched_at = sqlc.arg(fetched_at)::timestamp,
+  next_fetch_at = sqlc.arg(lease_until)::timestamp,
+  updated_at = sqlc.arg(fetched_at)::timestamp
+WHERE id IN (
+    SELECT id
+    FROM feeds
+    WHERE disabled_at IS NULL
+      AND (
+        user_id = sqlc.narg(user_id)
+        OR (
+          sqlc.narg(user_id)::uuid IS NULL
+          AND EXISTS (
+            SELECT 1
+            FROM feed_follows ff
+            WHERE ff.feed_id = feeds.id
+          )
+        )
+      )
+      AND (
+        next_fetch_at IS NULL
+        OR next_fetch_at <= sqlc.arg(due_before)::timestamp
+      )
+    ORDER BY next_fetch_at ASC NULLS FIRST
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING *;
+
+-- name: ScheduleNextFetch :exec
+UPDATE feeds
+SET refresh_interval_seconds = $1,
+  next_fetch_at = $2,
+  updated_at = $3
+WHERE id = $4;
+
+-- name: SetFeedCacheValidators :exec
+UPDATE feeds
+SET etag = $1,
+  last_modified = $2,
+  updated_at = $3
+WHERE id = $4;
+
+-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $1,
+  link = $2,
+  description = $3,
+  language = $4,
+  image_url = $5,
+  updated_at = $6
+WHERE id = $7;
+
+-- name: RecordFeedSuccess :exec
 UPDATE feeds
-SET last_fetched_at = $1,
+SET error_count = 0,
+  last_error = NULL,
+  disabled_at = NULL,
+  gone_at = NULL,
+  last_success_at = $1,
   updated_at = $2
-WHERE id = $3
-  AND user_id = $4;
+WHERE id = $3;
 
--- name: GetNextFeedToFetch :one
-SELECT *
-FROM feeds
-WHERE user_id = $1
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+-- name: RecordFeedFailure :exec
+UPDATE feeds
+SET error_count = error_count + 1,
+  last_error = sqlc.arg(last_error),
+  next_fetch_at = sqlc.arg(next_fetch_at),
+  disabled_at = COALESCE(disabled_at, sqlc.narg(disabled_at)::timestamp),
+  updated_at = sqlc.arg(updated_at)
+WHERE id = sqlc.arg(id);
+
+-- name: MarkFeedGone :exec
+UPDATE feeds
+SET error_count = error_count + 1,
+  last_error = $1,
+  gone_at = $2,
+  disabled_at = $2,
+  updated_at = $2
+WHERE id = $3;
+
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET url = $1,
+  updated_at = $2
+WHERE id = $3;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
\ No newline at end of file
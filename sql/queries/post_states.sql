@@ -0,0 +1,141 @@
+-- name: SetPostRead :execrows
+INSERT INTO post_states (
+    user_id,
+    post_id,
+    read,
+    read_at,
+    created_at,
+    updated_at
+  )
+SELECT ff.user_id,
+  p.id,
+  sqlc.arg(read)::boolean,
+  sqlc.narg(read_at)::timestamp,
+  sqlc.arg(updated_at)::timestamp,
+  sqlc.arg(updated_at)::timestamp
+FROM posts p
+  JOIN feed_follows ff ON ff.feed_id = p.feed_id
+WHERE ff.user_id = sqlc.arg(user_id)
+  AND p.id = sqlc.arg(post_id)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read = EXCLUDED.read,
+  read_at = EXCLUDED.read_at,
+  updated_at = EXCLUDED.updated_at;
+
+-- name: MarkPostsRead :execrows
+INSERT INTO post_states (
+    user_id,
+    post_id,
+    read,
+    read_at,
+    created_at,
+    updated_at
+  )
+SELECT ff.user_id,
+  p.id,
+  TRUE,
+  sqlc.arg(read_at)::timestamp,
+  sqlc.arg(read_at)::timestamp,
+  sqlc.arg(read_at)::timestamp
+FROM posts p
+  JOIN feed_follows ff ON ff.feed_id = p.feed_id
+WHERE ff.user_id = sqlc.arg(user_id)
+  AND (
+    sqlc.narg(feed_id)::uuid IS NULL
+    OR p.feed_id = sqlc.narg(feed_id)
+  )
+  AND (
+    sqlc.narg(published_before)::timestamp IS NULL
+    OR p.published_at < sqlc.narg(published_before)
+  )
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read = TRUE,
+  read_at = EXCLUDED.read_at,
+  updated_at = EXCLUDED.updated_at
+WHERE NOT post_states.read;
+
+-- name: StarPost :execrows
+INSERT INTO post_states (
+    user_id,
+    post_id,
+    starred,
+    starred_at,
+    created_at,
+    updated_at
+  )
+SELECT ff.user_id,
+  p.id,
+  TRUE,
+  sqlc.arg(starred_at)::timestamp,
+  sqlc.arg(starred_at)::timestamp,
+  sqlc.arg(starred_at)::timestamp
+FROM posts p
+  JOIN feed_follows ff ON ff.feed_id = p.feed_id
+WHERE ff.user_id = sqlc.arg(user_id)
+  AND p.id = sqlc.arg(post_id)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET starred = TRUE,
+  starred_at = COALESCE(post_states.starred_at, EXCLUDED.starred_at),
+  updated_at = EXCLUDED.updated_at;
+
+-- name: UnstarPost :execrows
+UPDATE post_states
+SET starred = FALSE,
+  starred_at = NULL,
+  updated_at = $1
+WHERE user_id = $2
+  AND post_id = $3
+  AND starred;
+
+-- name: GetStarredPostsForUser :many
+SELECT p.id,
+  p.title,
+  p.url,
+  p.description,
+  p.published_at,
+  p.feed_id,
+  p.created_at,
+  p.updated_at,
+  p.guid,
+  p.content_hash,
+  p.revised_at,
+  ps.starred_at
+FROM posts p
+  JOIN post_states ps ON ps.post_id = p.id
+WHERE ps.user_id = sqlc.arg(user_id)
+  AND ps.starred
+ORDER BY ps.starred_at DESC
+LIMIT sqlc.arg(post_limit);
+
+-- name: MovePostStates :exec
+INSERT INTO post_states (
+    user_id,
+    post_id,
+    read,
+    read_at,
+    starred,
+    starred_at,
+    created_at,
+    updated_at
+  )
+SELECT ps.user_id,
+  dst.id,
+  ps.read,
+  ps.read_at,
+  ps.starred,
+  ps.starred_at,
+  ps.created_at,
+  ps.updated_at
+FROM post_states ps
+  JOIN posts src ON src.id = ps.post_id
+  JOIN posts dst ON dst.guid = src.guid
+  AND dst.feed_id = sqlc.arg(to_feed_id)
+WHERE src.feed_id = sqlc.arg(from_feed_id)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read = post_states.read
+  OR EXCLUDED.read,
+  read_at = COALESCE(post_states.read_at, EXCLUDED.read_at),
+  starred = post_states.starred
+  OR EXCLUDED.starred,
+  starred_at = COALESCE(post_states.starred_at, EXCLUDED.starred_at),
+  updated_at = GREATEST(post_states.updated_at, EXCLUDED.updated_at);
\ No newline at end of file
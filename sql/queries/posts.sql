@@ -1,4 +1,4 @@
--- name: CreatePost :exec
+-- name: CreatePost :one
 INSERT INTO posts (
     id,
     title,
@@ -7,14 +7,107 @@ INSERT INTO posts (
     published_at,
     feed_id,
     created_at,
-    updated_at
+    updated_at,
+    guid,
+    content_hash
   )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = EXCLUDED.title,
+  url = EXCLUDED.url,
+  description = EXCLUDED.description,
+  published_at = EXCLUDED.published_at,
+  content_hash = EXCLUDED.content_hash,
+  updated_at = EXCLUDED.updated_at,
+  revised_at = CASE
+    WHEN posts.content_hash = '' THEN posts.revised_at
+    ELSE EXCLUDED.updated_at
+  END
+WHERE posts.content_hash <> EXCLUDED.content_hash
+RETURNING (xmax = 0) AS inserted;
 
 -- name: GetPostsForUser :many
-SELECT p.*
+SELECT p.id,
+  p.title,
+  p.url,
+  p.description,
+  p.published_at,
+  p.feed_id,
+  p.created_at,
+  p.updated_at,
+  p.guid,
+  p.content_hash,
+  p.revised_at,
+  COALESCE(ps.read, FALSE) AS read,
+  COALESCE(ps.starred, FALSE) AS starred
 FROM posts p
   JOIN feed_follows ff ON ff.feed_id = p.feed_id
-WHERE ff.user_id = $1
+  LEFT JOIN post_states ps ON ps.post_id = p.id
+  AND ps.user_id = ff.user_id
+WHERE ff.user_id = sqlc.arg(user_id)
+  AND (
+    NOT sqlc.arg(unread_only)::boolean
+    OR NOT COALESCE(ps.read, FALSE)
+  )
 ORDER BY p.published_at DESC
-LIMIT $2;
\ No newline at end of file
+LIMIT sqlc.arg(post_limit);
+
+-- name: SearchPostsForUser :many
+SELECT p.id,
+  p.title,
+  p.url,
+  p.published_at,
+  f.name AS feed_name,
+  ts_rank(
+    p.search_vector,
+    websearch_to_tsquery('english', sqlc.arg(query)::text)
+  ) AS rank,
+  ts_headline(
+    'english',
+    p.description,
+    websearch_to_tsquery('english', sqlc.arg(query)::text),
+    'StartSel=**, StopSel=**, MaxFragments=2, FragmentDelimiter=" ... "'
+  ) AS snippet
+FROM posts p
+  JOIN feed_follows ff ON ff.feed_id = p.feed_id
+  JOIN feeds f ON f.id = p.feed_id
+WHERE ff.user_id = sqlc.arg(user_id)
+  AND p.search_vector @@ websearch_to_tsquery('english', sqlc.arg(query)::text)
+  AND (
+    sqlc.narg(feed_id)::uuid IS NULL
+    OR p.feed_id = sqlc.narg(feed_id)
+  )
+  AND (
+    sqlc.narg(published_after)::timestamp IS NULL
+    OR p.published_at >= sqlc.narg(published_after)
+  )
+  AND (
+    sqlc.narg(published_before)::timestamp IS NULL
+    OR p.published_at < sqlc.narg(published_before)
+  )
+ORDER BY rank DESC,
+  p.published_at DESC
+LIMIT sqlc.arg(post_limit);
+
+-- name: UpdateLegacyPostGUID :exec
+UPDATE posts
+SET guid = sqlc.arg(guid)
+WHERE feed_id = sqlc.arg(feed_id)
+  AND guid = sqlc.arg(url)
+  AND url = sqlc.arg(url)
+  AND NOT EXISTS (
+    SELECT 1
+    FROM posts existing
+    WHERE existing.feed_id = sqlc.arg(feed_id)
+      AND existing.guid = sqlc.arg(guid)
+  );
+
+-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id)
+  AND guid NOT IN (
+    SELECT guid
+    FROM posts
+    WHERE feed_id = sqlc.arg(to_feed_id)
+  );
\ No newline at end of file
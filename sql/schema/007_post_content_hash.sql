@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN content_hash TEXT NOT NULL DEFAULT '',
+ADD COLUMN revised_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN revised_at,
+DROP COLUMN content_hash;
\ No newline at end of file
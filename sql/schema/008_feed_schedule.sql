@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN refresh_interval_seconds INTEGER,
+ADD COLUMN next_fetch_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN next_fetch_at,
+DROP COLUMN refresh_interval_seconds;
\ No newline at end of file
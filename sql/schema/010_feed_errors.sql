@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN error_count INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN last_error TEXT,
+ADD COLUMN last_success_at TIMESTAMP,
+ADD COLUMN disabled_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN disabled_at,
+DROP COLUMN last_success_at,
+DROP COLUMN last_error,
+DROP COLUMN error_count;
\ No newline at end of file
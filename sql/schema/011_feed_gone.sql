@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN gone_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN gone_at;
\ No newline at end of file
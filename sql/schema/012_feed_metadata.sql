@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN title TEXT,
+ADD COLUMN link TEXT,
+ADD COLUMN description TEXT,
+ADD COLUMN language TEXT,
+ADD COLUMN image_url TEXT;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN image_url,
+DROP COLUMN language,
+DROP COLUMN description,
+DROP COLUMN link,
+DROP COLUMN title;
\ No newline at end of file
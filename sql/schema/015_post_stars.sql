@@ -0,0 +1,39 @@
+-- +goose Up
+ALTER TABLE post_states
+ADD COLUMN starred BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN starred_at TIMESTAMP;
+
+-- Deleting a starred post is silently skipped, so cleanup queries never have
+-- to remember to exclude them. Posts still go when their feed is deleted.
+-- +goose StatementBegin
+CREATE FUNCTION keep_starred_posts() RETURNS trigger AS $$
+BEGIN
+  IF EXISTS (
+    SELECT 1
+    FROM post_states
+    WHERE post_id = OLD.id
+      AND starred
+  )
+  AND EXISTS (
+    SELECT 1
+    FROM feeds
+    WHERE id = OLD.feed_id
+  ) THEN
+    RETURN NULL;
+  END IF;
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER posts_keep_starred BEFORE DELETE ON posts
+FOR EACH ROW EXECUTE FUNCTION keep_starred_posts();
+
+-- +goose Down
+DROP TRIGGER posts_keep_starred ON posts;
+
+DROP FUNCTION keep_starred_posts();
+
+ALTER TABLE post_states
+DROP COLUMN starred_at,
+DROP COLUMN starred;
\ No newline at end of file
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', title), 'A') ||
+    setweight(to_tsvector('english', description), 'B')
+  ) STORED;
+
+CREATE INDEX posts_search_vector_idx ON posts USING GIN (search_vector);
+
+-- +goose Down
+DROP INDEX posts_search_vector_idx;
+
+ALTER TABLE posts DROP COLUMN search_vector;
\ No newline at end of file